@@ -2,16 +2,42 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	ssogrpc "url-shortener/internal/clients/sso/grpc"
 	"url-shortener/internal/config"
+	"url-shortener/internal/healthcheck"
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/url/redirect"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/http-server/middleware/ratelimit"
 	"url-shortener/internal/http-server/router"
+	"url-shortener/internal/lib/breaker"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/hmackeys"
+	"url-shortener/internal/lib/jwks"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/throttle"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/metadata"
 	"url-shortener/internal/storage/sqlite"
 
 	"log/slog"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
@@ -26,19 +52,55 @@ func main() {
 
 	logger.Info("starting url-shortener", "env", cfg.Env)
 
-	ssoClient, err := ssogrpc.New(
-		context.Background(),
-		logger,
-		cfg.Clients.SSO.Address,
-		cfg.Clients.SSO.Timeout,
-		cfg.Clients.SSO.RetriesCount,
+	adminChecker, healthChecks, err := setupAdmins(context.Background(), logger, cfg)
+	if err != nil {
+		logger.Error("failed to set up admin checks", sl.Err(err))
+		os.Exit(1)
+	}
+
+	roles, err := setupRoles(adminChecker, cfg.Roles)
+	if err != nil {
+		logger.Error("invalid roles", sl.Err(err))
+		os.Exit(1)
+	}
+
+	allowedDomains := domains.New(cfg.Domains.Default, cfg.Domains.Allowed)
+
+	publicBaseURL, err := url.Parse(cfg.HTTPServer.PublicBaseURL)
+	if err != nil || publicBaseURL.Scheme == "" || publicBaseURL.Host == "" {
+		logger.Error("invalid public base url", slog.String("public_base_url", cfg.HTTPServer.PublicBaseURL))
+		os.Exit(1)
+	}
+
+	cookieSecret := []byte(cfg.Links.CookieSecret)
+	if len(cookieSecret) == 0 {
+		logger.Warn("links.cookie_secret is empty, link password cookies will not survive a restart")
+		cookieSecret = make([]byte, 32)
+		if _, err := rand.Read(cookieSecret); err != nil {
+			logger.Error("failed to generate cookie secret", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	passwords := redirect.NewPasswordGate(
+		cookieSecret,
+		cfg.Links.PasswordCookieTTL,
+		throttle.New(cfg.Links.PasswordMaxAttempts, cfg.Links.PasswordLockout),
 	)
+
+	policy, err := urlpolicy.New(urlpolicy.Options{
+		Schemes:        cfg.URLPolicy.Schemes,
+		ListsFile:      cfg.URLPolicy.ListsFile,
+		DenyPrivate:    cfg.URLPolicy.DenyPrivate,
+		ResolveTimeout: cfg.URLPolicy.ResolveTimeout,
+	})
 	if err != nil {
-		logger.Error("failed to create sso client", sl.Err(err))
+		logger.Error("failed to load url policy", sl.Err(err))
 		os.Exit(1)
 	}
+	go policy.Watch(context.Background(), logger, cfg.URLPolicy.ReloadInterval)
 
-	storage, err := sqlite.New(cfg.StoragePath)
+	storage, err := sqlite.New(cfg.StoragePath, allowedDomains.Default())
 	if err != nil {
 		logger.Error(
 			"Не удалось инициализировать базу данных", sl.Err(err))
@@ -46,15 +108,77 @@ func main() {
 	}
 
 	logger.Info("База инициализирована", "path", cfg.StoragePath)
+	if !storage.FullTextSearch() {
+		logger.Warn("SQLite is built without FTS5, search falls back to slow substring matching; build with -tags sqlite_fts5 or make build")
+	}
+
+	revocations, err := auth.NewRevocations(storage, cfg.JWT.Leeway)
+	if err != nil {
+		logger.Error("failed to load token revocations", sl.Err(err))
+		os.Exit(1)
+	}
+	go revocations.Watch(context.Background(), logger, cfg.JWT.RevocationRefresh)
+
+	tokens, err := setupTokens(context.Background(), logger, cfg, revocations)
+	if err != nil {
+		logger.Error("failed to set up token verification", sl.Err(err))
+		os.Exit(1)
+	}
+
+	targetClient := newTargetClient(cfg.URLPolicy.DenyPrivate)
+
+	if cfg.HealthCheck.Enabled {
+		checker := healthcheck.New(logger, storage, targetClient, healthcheck.Options{
+			Interval:     cfg.HealthCheck.Interval,
+			PollInterval: cfg.HealthCheck.PollInterval,
+			Timeout:      cfg.HealthCheck.Timeout,
+			Concurrency:  cfg.HealthCheck.Concurrency,
+			HostInterval: cfg.HealthCheck.HostInterval,
+			BatchSize:    cfg.HealthCheck.BatchSize,
+			Policy:       policy,
+		})
+		go checker.Run(context.Background())
+	}
+
+	// A nil fetcher turns metadata fetching off in the save handler.
+	var fetcher save.MetadataFetcher
+	if cfg.Metadata.Enabled {
+		f := metadata.New(logger, storage, targetClient, metadata.Options{
+			Timeout:     cfg.Metadata.Timeout,
+			MaxBodySize: cfg.Metadata.MaxBodySize,
+			Workers:     cfg.Metadata.Workers,
+			QueueSize:   cfg.Metadata.QueueSize,
+			Policy:      policy,
+		})
+		go f.Run(context.Background())
+		fetcher = f
+	}
 
 	r := router.New(
 		logger,
 		storage,
-		ssoClient,
-		cfg.AppSecret,
+		roles,
+		allowedDomains,
+		publicBaseURL,
+		passwords,
+		policy,
+		fetcher,
+		tokens,
+		revocations,
 		cfg.Clients.SSO.Timeout,
+		router.RateLimits{
+			Create:   ratelimit.Limit(cfg.RateLimit.Create),
+			Redirect: ratelimit.Limit(cfg.RateLimit.Redirect),
+			API:      ratelimit.Limit(cfg.RateLimit.API),
+			Auth:     ratelimit.Limit(cfg.RateLimit.Auth),
+		},
+		healthChecks,
 	)
 
+	if cfg.HTTPServer.DebugAddress != "" {
+		go serveDebug(logger, cfg.HTTPServer.DebugAddress)
+	}
+
 	logger.Info("server started", slog.String("address", cfg.HTTPServer.Address))
 
 	server := &http.Server{
@@ -71,6 +195,238 @@ func main() {
 
 }
 
+// setupAdmins combines the admin sources of the config, and returns the
+// health checks of the services they depend on.
+func setupAdmins(ctx context.Context, log *slog.Logger, cfg *config.Config) (auth.AdminChecker, map[string]health.Check, error) {
+	if len(cfg.Admins.Sources) == 0 {
+		return nil, nil, errors.New("admins: no sources")
+	}
+
+	var checkers auth.AnyAdmins
+	checks := make(map[string]health.Check)
+	for _, source := range cfg.Admins.Sources {
+		switch source {
+		case "static":
+			checkers = append(checkers, auth.NewStaticAdmins(cfg.Admins.Users))
+		case "jwt":
+			checkers = append(checkers, auth.NewClaimAdmins(auth.ClaimAdminsOptions{
+				RolesClaim: cfg.Admins.RolesClaim,
+				AdminRole:  cfg.Admins.AdminRole,
+				AdminClaim: cfg.Admins.AdminClaim,
+			}))
+		case "sso":
+			sso, check, err := setupSSOAdmins(ctx, log, cfg.Clients.SSO)
+			if err != nil {
+				return nil, nil, err
+			}
+			checkers = append(checkers, sso)
+			checks["sso"] = check
+		default:
+			return nil, nil, fmt.Errorf("admins: unknown source %q", source)
+		}
+	}
+
+	if len(checkers) == 1 {
+		return checkers[0], checks, nil
+	}
+	return checkers, checks, nil
+}
+
+// setupSSOAdmins asks the SSO service, through a circuit breaker and a
+// cache.
+func setupSSOAdmins(ctx context.Context, log *slog.Logger, cfg config.Client) (auth.AdminChecker, health.Check, error) {
+	ssoBreaker := breaker.New(breaker.Options{
+		FailureThreshold:  cfg.Breaker.FailureThreshold,
+		OpenTimeout:       cfg.Breaker.OpenTimeout,
+		HalfOpenSuccesses: cfg.Breaker.HalfOpenSuccesses,
+		IsFailure:         ssogrpc.IsUnavailable,
+		OnStateChange: func(from, to breaker.State) {
+			log.Warn("sso circuit breaker state changed",
+				slog.String("from", from.String()),
+				slog.String("to", to.String()),
+			)
+		},
+	})
+
+	ssoCreds, err := setupSSOCredentials(ctx, log, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sso tls: %w", err)
+	}
+
+	ssoClient, err := ssogrpc.New(
+		ctx,
+		log,
+		cfg.Address,
+		cfg.Timeout,
+		cfg.RetriesCount,
+		ssoBreaker,
+		ssoCreds,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sso client: %w", err)
+	}
+
+	adminCache := auth.NewCachedAdminChecker(ssoClient, auth.AdminCacheOptions{
+		PositiveTTL: cfg.AdminCache.PositiveTTL,
+		NegativeTTL: cfg.AdminCache.NegativeTTL,
+		StaleFor:    cfg.AdminCache.ServeStaleFor,
+	})
+	expvar.Publish("admin_cache", expvar.Func(func() any { return adminCache.Stats() }))
+	go invalidateOnHangup(log, adminCache)
+
+	check := func() health.Component {
+		state := ssoBreaker.State()
+		return health.Component{
+			Healthy:    state == breaker.Closed,
+			State:      state.String(),
+			RetryAfter: int(math.Ceil(ssoBreaker.RetryAfter().Seconds())),
+		}
+	}
+
+	return adminCache, check, nil
+}
+
+func setupSSOCredentials(ctx context.Context, log *slog.Logger, cfg config.Client) (credentials.TransportCredentials, error) {
+	if cfg.Insecure {
+		log.Warn("connecting to sso without tls")
+		return insecure.NewCredentials(), nil
+	}
+
+	creds, err := ssogrpc.NewTLSCredentials(ssogrpc.TLSOptions{
+		CAFile:     cfg.TLS.CAFile,
+		CertFile:   cfg.TLS.CertFile,
+		KeyFile:    cfg.TLS.KeyFile,
+		ServerName: cfg.TLS.ServerName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	go creds.Watch(ctx, log, cfg.TLS.ReloadInterval)
+
+	return creds, nil
+}
+
+func setupRoles(adminChecker auth.AdminChecker, cfg config.Roles) (*auth.Roles, error) {
+	defaultRole, err := auth.ParseRole(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+
+	users := make(map[int64]auth.Role, len(cfg.Users))
+	for id, name := range cfg.Users {
+		role, err := auth.ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("user %d: %w", id, err)
+		}
+		users[id] = role
+	}
+
+	return auth.NewRoles(adminChecker, defaultRole, users), nil
+}
+
+func setupTokens(
+	ctx context.Context,
+	log *slog.Logger,
+	cfg *config.Config,
+	revocations auth.RevocationChecker,
+) (*auth.JWTVerifier, error) {
+	opts := auth.JWTOptions{
+		Issuer:      cfg.JWT.Issuer,
+		Audience:    cfg.JWT.Audience,
+		Leeway:      cfg.JWT.Leeway,
+		UserIDClaim: cfg.JWT.UserIDClaim,
+		Revocations: revocations,
+	}
+	if cfg.JWT.HS256 {
+		keys, err := setupHMACKeys(cfg)
+		if err != nil {
+			return nil, err
+		}
+		go keys.Watch(ctx, log, cfg.JWT.HMACKeys.ReloadInterval)
+		opts.HMACKeys = keys
+
+		active, _ := keys.Active()
+		log.Info("hs256 signing keys loaded", slog.String("active", active))
+	}
+
+	if cfg.JWT.JWKS.File != "" || cfg.JWT.JWKS.URL != "" {
+		keys, err := jwks.New(ctx, jwks.Options{
+			File:    cfg.JWT.JWKS.File,
+			URL:     cfg.JWT.JWKS.URL,
+			Timeout: cfg.JWT.JWKS.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		go keys.Watch(ctx, log, cfg.JWT.JWKS.RefreshInterval)
+		opts.Keys = keys
+	}
+
+	return auth.NewJWTVerifier(opts)
+}
+
+// setupHMACKeys loads the HS256 secrets from the secrets file or the
+// config, falling back to AppSecret as the only key.
+func setupHMACKeys(cfg *config.Config) (*hmackeys.Source, error) {
+	c := cfg.JWT.HMACKeys
+	if c.File != "" {
+		if len(c.Keys) > 0 {
+			return nil, errors.New("hmac keys: set either file or keys")
+		}
+		return hmackeys.New(hmackeys.Options{File: c.File})
+	}
+
+	active, keys := c.Active, c.Keys
+	if len(keys) == 0 {
+		active, keys = "app_secret", map[string]string{"app_secret": cfg.AppSecret}
+	}
+	set, err := hmackeys.NewSet(active, keys)
+	if err != nil {
+		return nil, fmt.Errorf("hmac keys: %w", err)
+	}
+	return hmackeys.New(hmackeys.Options{Static: set})
+}
+
+// newTargetClient returns the client that requests link targets. With
+// denyPrivate it refuses to connect to non-public addresses, whatever a
+// host resolves to at that moment.
+func newTargetClient(denyPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if denyPrivate {
+		// A proxy would connect to the target on our behalf, unchecked.
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   urlpolicy.DialControl,
+		}).DialContext
+	}
+	return &http.Client{Transport: transport}
+}
+
+// serveDebug serves the expvar metrics, such as admin_cache.
+func serveDebug(log *slog.Logger, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	log.Info("debug server started", slog.String("address", address))
+	server := &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if err := server.ListenAndServe(); err != nil {
+		log.Error("debug server failed", sl.Err(err))
+	}
+}
+
+// invalidateOnHangup clears the admin cache on SIGHUP, e.g. right after
+// an admin was removed in SSO.
+func invalidateOnHangup(log *slog.Logger, cache *auth.CachedAdminChecker) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		cache.InvalidateAll()
+		log.Info("admin cache cleared")
+	}
+}
+
 func setupLogger(env string) *slog.Logger {
 	var logger *slog.Logger
 
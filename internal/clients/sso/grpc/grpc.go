@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -11,20 +12,29 @@ import (
 	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/lib/breaker"
 )
 
 type Client struct {
-	api ssov1.AuthClient
-	log *slog.Logger
+	api     ssov1.AuthClient
+	log     *slog.Logger
+	breaker *breaker.Breaker
 }
 
+// New connects to SSO with creds, e.g. TLSCredentials or
+// insecure.NewCredentials(). Calls go through cb, which may be nil; see
+// IsUnavailable for the errors it should count.
 func New(
 	ctx context.Context,
 	log *slog.Logger,
 	addr string,
 	timeout time.Duration,
 	retriesCount int,
+	cb *breaker.Breaker,
+	creds credentials.TransportCredentials,
 ) (*Client, error) {
 	const op = "grpc.New"
 
@@ -40,7 +50,7 @@ func New(
 
 	conn, err := grpc.NewClient(
 		addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithChainUnaryInterceptor(
 			grpcretry.UnaryClientInterceptor(retryOpts...),
 			grpclog.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
@@ -50,8 +60,9 @@ func New(
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	return &Client{
-		api: ssov1.NewAuthClient(conn),
-		log: log,
+		api:     ssov1.NewAuthClient(conn),
+		log:     log,
+		breaker: cb,
 	}, nil
 }
 
@@ -64,11 +75,44 @@ func InterceptorLogger(l *slog.Logger) grpclog.Logger {
 func (c *Client) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	const op = "Client.IsAdmin"
 
-	resp, err := c.api.IsAdmin(ctx, &ssov1.IsAdminRequest{
-		UserId: userID,
-	})
+	var isAdmin bool
+	call := func() error {
+		resp, err := c.api.IsAdmin(ctx, &ssov1.IsAdminRequest{
+			UserId: userID,
+		})
+		if err != nil {
+			return err
+		}
+		isAdmin = resp.IsAdmin
+		return nil
+	}
+
+	var err error
+	if c.breaker != nil {
+		err = c.breaker.Do(call)
+	} else {
+		err = call()
+	}
 	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
-	return resp.IsAdmin, nil
+	return isAdmin, nil
+}
+
+// IsUnavailable reports whether err means SSO could not answer, as
+// opposed to answering with an error about the request.
+func IsUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unknown, codes.Aborted:
+		return true
+	default:
+		return false
+	}
 }
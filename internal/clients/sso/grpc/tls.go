@@ -0,0 +1,182 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// TLSOptions secure the connection to SSO. All files are PEM encoded.
+type TLSOptions struct {
+	// CAFile holds the CAs trusted to sign the SSO server certificate.
+	// The system roots are used when it is empty.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate for mutual TLS;
+	// leave both empty to not present one.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name the server certificate must match,
+	// which defaults to the host of the address.
+	ServerName string
+}
+
+// TLSCredentials are gRPC transport credentials whose certificates are
+// re-read from disk by Reload. New connections use the latest ones;
+// established connections are not interrupted.
+type TLSCredentials struct {
+	opts TLSOptions
+
+	config atomic.Pointer[tls.Config]
+
+	// mu serializes reloads.
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+}
+
+func NewTLSCredentials(opts TLSOptions) (*TLSCredentials, error) {
+	const op = "grpc.NewTLSCredentials"
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("%s: cert file and key file must be set together", op)
+	}
+
+	c := &TLSCredentials{opts: opts}
+	if _, err := c.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return c, nil
+}
+
+func (c *TLSCredentials) files() []string {
+	var files []string
+	for _, f := range []string{c.opts.CAFile, c.opts.CertFile, c.opts.KeyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Reload re-reads the files if any of them changed since the last load.
+// It reports whether the certificates were replaced. On error the
+// previous ones stay in use.
+func (c *TLSCredentials) Reload() (bool, error) {
+	const op = "grpc.TLSCredentials.Reload"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTimes := make(map[string]time.Time)
+	changed := c.config.Load() == nil
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		modTimes[f] = info.ModTime()
+		if !info.ModTime().Equal(c.modTimes[f]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.opts.ServerName,
+	}
+
+	if c.opts.CAFile != "" {
+		pem, err := os.ReadFile(c.opts.CAFile)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("%s: no certificates in %s", op, c.opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.opts.CertFile, c.opts.KeyFile)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	c.config.Store(cfg)
+	c.modTimes = modTimes
+
+	return true, nil
+}
+
+// Watch reloads the certificates every interval until ctx is done.
+// Failures are logged and the previous certificates stay in use.
+func (c *TLSCredentials) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	log = log.With(slog.String("component", "sso-tls"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := c.Reload()
+			if err != nil {
+				log.Error("failed to reload certificates", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("certificates reloaded")
+			}
+		}
+	}
+}
+
+func (c *TLSCredentials) current() credentials.TransportCredentials {
+	return credentials.NewTLS(c.config.Load().Clone())
+}
+
+func (c *TLSCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return c.current().ClientHandshake(ctx, authority, conn)
+}
+
+func (c *TLSCredentials) ServerHandshake(net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("grpc.TLSCredentials: server handshakes are not supported")
+}
+
+func (c *TLSCredentials) Info() credentials.ProtocolInfo {
+	return c.current().Info()
+}
+
+// Clone returns c itself: it is safe for concurrent use and has no
+// settings for a clone to change.
+func (c *TLSCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+// OverrideServerName is deprecated in gRPC; use TLSOptions.ServerName.
+func (c *TLSCredentials) OverrideServerName(string) error {
+	return errors.New("grpc.TLSCredentials: use TLSOptions.ServerName")
+}
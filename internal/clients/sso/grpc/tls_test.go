@@ -0,0 +1,251 @@
+package grpc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ssov1 "github.com/Svetlov-al/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	ssogrpc "url-shortener/internal/clients/sso/grpc"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return authority{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a PEM certificate and key signed by a.
+func (a authority) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+type adminServer struct {
+	ssov1.UnimplementedAuthServer
+}
+
+func (adminServer) IsAdmin(_ context.Context, req *ssov1.IsAdminRequest) (*ssov1.IsAdminResponse, error) {
+	return &ssov1.IsAdminResponse{IsAdmin: req.UserId == 1}, nil
+}
+
+// startServer serves SSO over TLS for "sso.internal", requiring client
+// certificates signed by clientCA.
+func startServer(t *testing.T, serverCA authority, clientCA authority) string {
+	t.Helper()
+
+	certPEM, keyPEM := serverCA.issue(t, "sso.internal", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCA.cert)
+
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})))
+	ssov1.RegisterAuthServer(srv, adminServer{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+func writeFile(t *testing.T, dir string, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func newClient(t *testing.T, addr string, creds credentials.TransportCredentials) *ssogrpc.Client {
+	t.Helper()
+	c, err := ssogrpc.New(context.Background(), slogdiscard.NewDiscardLogger(), addr, 2*time.Second, 0, nil, creds)
+	require.NoError(t, err)
+	return c
+}
+
+func TestTLSCredentials(t *testing.T) {
+	serverCA := newAuthority(t, "server ca")
+	clientCA := newAuthority(t, "client ca")
+	otherCA := newAuthority(t, "other ca")
+	addr := startServer(t, serverCA, clientCA)
+
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", serverCA.pem)
+	otherCAFile := writeFile(t, dir, "other-ca.pem", otherCA.pem)
+	certPEM, keyPEM := clientCA.issue(t, "url-shortener", x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, dir, "client.pem", certPEM)
+	keyFile := writeFile(t, dir, "client-key.pem", keyPEM)
+
+	cases := []struct {
+		name    string
+		opts    ssogrpc.TLSOptions
+		wantErr bool
+	}{
+		{
+			name: "Mutual TLS",
+			opts: ssogrpc.TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "sso.internal"},
+		},
+		{
+			name:    "Server name not overridden",
+			opts:    ssogrpc.TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+			wantErr: true,
+		},
+		{
+			name:    "Untrusted server",
+			opts:    ssogrpc.TLSOptions{CAFile: otherCAFile, CertFile: certFile, KeyFile: keyFile, ServerName: "sso.internal"},
+			wantErr: true,
+		},
+		{
+			name:    "No client certificate",
+			opts:    ssogrpc.TLSOptions{CAFile: caFile, ServerName: "sso.internal"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			creds, err := ssogrpc.NewTLSCredentials(tc.opts)
+			require.NoError(t, err)
+
+			isAdmin, err := newClient(t, addr, creds).IsAdmin(context.Background(), 1)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, isAdmin)
+		})
+	}
+}
+
+func TestTLSCredentials_Reload(t *testing.T) {
+	serverCA := newAuthority(t, "server ca")
+	clientCA := newAuthority(t, "client ca")
+	otherCA := newAuthority(t, "other ca")
+	addr := startServer(t, serverCA, clientCA)
+
+	// Start with a client certificate the server does not trust.
+	dir := t.TempDir()
+	certPEM, keyPEM := otherCA.issue(t, "url-shortener", x509.ExtKeyUsageClientAuth)
+	opts := ssogrpc.TLSOptions{
+		CAFile:     writeFile(t, dir, "ca.pem", serverCA.pem),
+		CertFile:   writeFile(t, dir, "client.pem", certPEM),
+		KeyFile:    writeFile(t, dir, "client-key.pem", keyPEM),
+		ServerName: "sso.internal",
+	}
+	creds, err := ssogrpc.NewTLSCredentials(opts)
+	require.NoError(t, err)
+	client := newClient(t, addr, creds)
+
+	_, err = client.IsAdmin(context.Background(), 1)
+	require.Error(t, err)
+
+	reloaded, err := creds.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files are not re-read")
+
+	certPEM, keyPEM = clientCA.issue(t, "url-shortener", x509.ExtKeyUsageClientAuth)
+	writeFile(t, dir, "client.pem", certPEM)
+	writeFile(t, dir, "client-key.pem", keyPEM)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(opts.CertFile, later, later))
+	require.NoError(t, os.Chtimes(opts.KeyFile, later, later))
+
+	reloaded, err = creds.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	// The same client reconnects with the new certificate, after the
+	// reconnect backoff of the failed attempt.
+	require.Eventually(t, func() bool {
+		isAdmin, err := client.IsAdmin(context.Background(), 1)
+		return err == nil && isAdmin
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestNewTLSCredentials_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ssogrpc.NewTLSCredentials(ssogrpc.TLSOptions{CertFile: filepath.Join(dir, "client.pem")})
+	require.Error(t, err, "cert without key")
+
+	_, err = ssogrpc.NewTLSCredentials(ssogrpc.TLSOptions{CAFile: filepath.Join(dir, "missing.pem")})
+	require.Error(t, err)
+
+	_, err = ssogrpc.NewTLSCredentials(ssogrpc.TLSOptions{CAFile: writeFile(t, dir, "empty.pem", []byte("no pem"))})
+	require.Error(t, err)
+}
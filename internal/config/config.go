@@ -12,6 +12,15 @@ type Config struct {
 	Env         string        `yaml:"env" env-default:"local"`
 	StoragePath string        `yaml:"storage_path" env-default:"./storage/storage.db" env-required:"true"`
 	HTTPServer  HTTPServer    `yaml:"http_server"`
+	Domains     Domains       `yaml:"domains"`
+	Links       Links         `yaml:"links"`
+	URLPolicy   URLPolicy     `yaml:"url_policy"`
+	HealthCheck HealthCheck   `yaml:"health_check"`
+	Metadata    Metadata      `yaml:"metadata"`
+	RateLimit   RateLimit     `yaml:"rate_limit"`
+	Roles       Roles         `yaml:"roles"`
+	Admins      Admins        `yaml:"admins"`
+	JWT         JWT           `yaml:"jwt"`
 	Clients     ClientsConfig `yaml:"clients"`
 	AppSecret   string        `yaml:"app_secret" env-required:"true" env:"APP_SECRET"`
 }
@@ -22,13 +31,193 @@ type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"0.0.0.0:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// PublicBaseURL is how clients reach the service, e.g. behind a proxy.
+	// It is used wherever a full short link is rendered.
+	PublicBaseURL string `yaml:"public_base_url" env-default:"http://localhost:8082"`
+	// DebugAddress serves runtime metrics at /debug/vars when set. Keep it
+	// off public networks.
+	DebugAddress string `yaml:"debug_address"`
+}
+
+type Domains struct {
+	Default string   `yaml:"default" env-default:"localhost:8082"`
+	Allowed []string `yaml:"allowed"`
+}
+
+type Links struct {
+	// CookieSecret signs the cookies that remember an entered link password.
+	// A random secret is generated on start when it is empty.
+	CookieSecret        string        `yaml:"cookie_secret" env:"LINK_COOKIE_SECRET"`
+	PasswordCookieTTL   time.Duration `yaml:"password_cookie_ttl" env-default:"30m"`
+	PasswordMaxAttempts int           `yaml:"password_max_attempts" env-default:"5"`
+	PasswordLockout     time.Duration `yaml:"password_lockout" env-default:"15m"`
+}
+
+type URLPolicy struct {
+	Schemes []string `yaml:"schemes" env-default:"http,https"`
+	// ListsFile holds domain allow/block lists and is re-read when it changes.
+	ListsFile      string        `yaml:"lists_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"30s"`
+	DenyPrivate    bool          `yaml:"deny_private" env-default:"true"`
+	ResolveTimeout time.Duration `yaml:"resolve_timeout" env-default:"2s"`
+}
+
+type HealthCheck struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// Interval is how often each link target is re-checked.
+	Interval     time.Duration `yaml:"interval" env-default:"1h"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1m"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	Concurrency  int           `yaml:"concurrency" env-default:"8"`
+	// HostInterval is the minimum delay between requests to the same host.
+	HostInterval time.Duration `yaml:"host_interval" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+}
+
+type Metadata struct {
+	// Enabled fetches the title, description, image and favicon of the
+	// target page after a link is saved.
+	Enabled     bool          `yaml:"enabled" env-default:"false"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"5s"`
+	MaxBodySize int64         `yaml:"max_body_size" env-default:"524288"`
+	Workers     int           `yaml:"workers" env-default:"2"`
+	QueueSize   int           `yaml:"queue_size" env-default:"100"`
+}
+
+// RateLimit limits requests per client IP, or per user once authenticated,
+// separately for each route group. A rule with zero requests disables
+// limiting for its group.
+type RateLimit struct {
+	// Create is POST /url. These requests also count against API.
+	Create RateLimitRule `yaml:"create"`
+	// Redirect covers following and previewing short links.
+	Redirect RateLimitRule `yaml:"redirect"`
+	// API covers the other /url endpoints.
+	API RateLimitRule `yaml:"api"`
+	// Auth covers all /url endpoints per client IP before authentication,
+	// so that failing credentials are limited too. Clients behind one NAT
+	// share it, so it should be well above API.
+	Auth RateLimitRule `yaml:"auth"`
+}
+
+// RateLimitRule allows bursts of Requests, refilled evenly over Per.
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+}
+
+// Roles assign permissions to users authenticated by the SSO service.
+// Users without a role in Users are admins if Admins says so; see package
+// auth for the roles.
+type Roles struct {
+	// Default is the role of every other user.
+	Default string `yaml:"default" env-default:"creator"`
+	// Users fixes the role per user ID. Admins is not asked about them, so
+	// they keep working while it is unavailable.
+	Users map[int64]string `yaml:"users"`
+}
+
+// Admins decide who is an admin. A user is one when any of Sources says
+// so; they are asked in order.
+type Admins struct {
+	// Sources are "static" for Users, "jwt" for the claims of the bearer
+	// token, and "sso" for the SSO service, which is not contacted
+	// unless listed.
+	Sources []string `yaml:"sources" env-default:"sso"`
+	Users   []int64  `yaml:"users"`
+	// RolesClaim is a list of roles that makes an admin when it contains
+	// AdminRole; AdminClaim is a boolean that makes one when true.
+	RolesClaim string `yaml:"roles_claim" env-default:"roles"`
+	AdminRole  string `yaml:"admin_role" env-default:"admin"`
+	AdminClaim string `yaml:"admin_claim" env-default:"is_admin"`
+}
+
+// JWT configures how bearer tokens issued by the SSO service are verified.
+type JWT struct {
+	// HS256 accepts tokens signed with HMACKeys, or AppSecret when none
+	// are set. Turn it off once the SSO service signs with asymmetric
+	// keys, so no secret need be shared.
+	HS256    bool     `yaml:"hs256" env-default:"true"`
+	HMACKeys HMACKeys `yaml:"hmac_keys"`
+	JWKS     JWKS     `yaml:"jwks"`
+	// Issuer and Audience are required in the iss and aud claims unless
+	// empty. Audience is the ID of this app in the SSO service.
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// Leeway tolerates clock skew with the SSO service.
+	Leeway time.Duration `yaml:"leeway" env-default:"30s"`
+	// UserIDClaim names the claim with the user ID.
+	UserIDClaim string `yaml:"user_id_claim" env-default:"user_id"`
+	// RevocationRefresh is how often revoked tokens are reloaded from the
+	// storage, picking up revocations made by other instances.
+	RevocationRefresh time.Duration `yaml:"revocation_refresh" env-default:"30s"`
+}
+
+// HMACKeys are the HS256 secrets by key ID, for rotating them without
+// invalidating every token at once: add the new key, make it active once
+// the SSO service has it, and remove the old one after its tokens
+// expired. Set either File, which is re-read when it changes, or Keys.
+type HMACKeys struct {
+	// File holds active and keys in the same format, and is best kept
+	// apart from this config with tighter permissions.
+	File           string            `yaml:"file"`
+	ReloadInterval time.Duration     `yaml:"reload_interval" env-default:"1m"`
+	Active         string            `yaml:"active"`
+	Keys           map[string]string `yaml:"keys"`
+}
+
+// JWKS is where the public keys for RS256, ES256 and EdDSA tokens are
+// published; set at most one of File and URL.
+type JWKS struct {
+	File            string        `yaml:"file"`
+	URL             string        `yaml:"url"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env-default:"15m"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"5s"`
 }
 
 type Client struct {
 	Address      string        `yaml:"address" env-default:"localhost:50051"`
 	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
 	RetriesCount int           `yaml:"retries_count" env-default:"3"`
-	Insecure     bool          `yaml:"insecure" env-default:"false"`
+	// Insecure connects without TLS; TLS is ignored then.
+	Insecure   bool       `yaml:"insecure" env-default:"false"`
+	TLS        ClientTLS  `yaml:"tls"`
+	AdminCache AdminCache `yaml:"admin_cache"`
+	Breaker    Breaker    `yaml:"breaker"`
+}
+
+// Breaker stops calling SSO after FailureThreshold consecutive failures
+// for OpenTimeout; meanwhile requests needing it get 503. A zero
+// threshold disables it.
+type Breaker struct {
+	FailureThreshold int           `yaml:"failure_threshold" env-default:"5"`
+	OpenTimeout      time.Duration `yaml:"open_timeout" env-default:"30s"`
+	// HalfOpenSuccesses is the number of successful trial calls after
+	// OpenTimeout that closes the breaker again.
+	HalfOpenSuccesses int `yaml:"half_open_successes" env-default:"1"`
+}
+
+// ClientTLS configures TLS to a gRPC server. Files are PEM encoded and
+// re-read when they change.
+type ClientTLS struct {
+	// CAFile replaces the system roots for verifying the server.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are the client certificate for mutual TLS.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerName overrides the name checked in the server certificate.
+	ServerName     string        `yaml:"server_name"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+// AdminCache keeps IsAdmin answers of the SSO service; zero TTLs turn
+// caching of that answer off. The cache is cleared on SIGHUP.
+type AdminCache struct {
+	PositiveTTL time.Duration `yaml:"positive_ttl" env-default:"1m"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" env-default:"30s"`
+	// ServeStaleFor answers with expired decisions for this long while
+	// SSO is unavailable; zero turns it off.
+	ServeStaleFor time.Duration `yaml:"serve_stale_for"`
 }
 
 type ClientsConfig struct {
@@ -0,0 +1,324 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/schedule"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+const (
+	userAgent = "url-shortener-healthcheck/1.0"
+	// maxBodyRead is how much of a GET response is drained so the
+	// connection can be reused.
+	maxBodyRead = 64 << 10
+
+	maxRedirects = 10
+)
+
+var errTooManyHops = errors.New("too many redirects")
+
+type Storage interface {
+	URLsToCheck(checkedBefore time.Time, limit int) ([]storage.URL, error)
+	SaveCheckResult(urlID int64, status int, checkErr string, checkedAt time.Time) error
+}
+
+type Options struct {
+	// Interval is how old the last check of a link must be to check it again.
+	Interval time.Duration
+	// PollInterval is how often Run looks for links due for a check.
+	PollInterval time.Duration
+	// Timeout bounds a single check, including the GET fallback.
+	Timeout     time.Duration
+	Concurrency int
+	// HostInterval is the minimum delay between checks of targets on the
+	// same host.
+	HostInterval time.Duration
+	// BatchSize limits the number of links checked per poll.
+	BatchSize int
+	// Policy, when set, is applied before each request and every redirect
+	// so that targets which now resolve to private networks are not
+	// contacted.
+	Policy urlpolicy.Policy
+}
+
+// Checker periodically requests link targets and records the outcome.
+type Checker struct {
+	log     *slog.Logger
+	storage Storage
+	client  *http.Client
+	opts    Options
+	now     func() time.Time
+}
+
+func New(log *slog.Logger, storage Storage, client *http.Client, opts Options) *Checker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	// The client is copied so that the redirect check does not leak into
+	// other users of it.
+	cl := *client
+	cl.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errTooManyHops
+		}
+		if opts.Policy != nil {
+			return opts.Policy.Check(req.Context(), req.URL.String())
+		}
+		return nil
+	}
+
+	return &Checker{
+		log:     log.With(slog.String("component", "healthcheck")),
+		storage: storage,
+		client:  &cl,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// Run checks due links every PollInterval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckDue(ctx); err != nil {
+			c.log.Error("health check pass failed", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckDue checks one batch of links that are due and stores the results.
+func (c *Checker) CheckDue(ctx context.Context) error {
+	const op = "healthcheck.CheckDue"
+
+	urls, err := c.storage.URLsToCheck(c.now().Add(-c.opts.Interval), c.opts.BatchSize)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	hosts := newHostLimiter(c.opts.HostInterval)
+	sem := make(chan struct{}, c.opts.Concurrency)
+
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			c.checkAndSave(ctx, hosts, u)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// checkAndSave checks every target the link can redirect to and stores
+// the result of the first that fails, or of the first target if none
+// does. The error names the failing target unless it is the first.
+func (c *Checker) checkAndSave(ctx context.Context, hosts *hostLimiter, u storage.URL) {
+	log := c.log.With(slog.Int64("url_id", u.ID), slog.String("url", u.URL))
+
+	var (
+		status   int
+		checkErr string
+	)
+	for i, target := range targets(u, c.now()) {
+		st, err := c.checkTarget(ctx, hosts, target)
+		if ctx.Err() != nil {
+			// Shutting down: the result says nothing about the target.
+			return
+		}
+		if err == nil && st < 400 {
+			if i == 0 {
+				status = st
+			}
+			continue
+		}
+
+		status = st
+		if err != nil {
+			checkErr = err.Error()
+			log.Info("target unreachable", slog.String("target", target), sl.Err(err))
+		} else {
+			log.Info("target broken", slog.String("target", target), slog.Int("status", st))
+		}
+		if i > 0 {
+			if checkErr == "" {
+				checkErr = fmt.Sprintf("status %d", st)
+			}
+			checkErr = "target " + target + ": " + checkErr
+		}
+		break
+	}
+
+	if err := c.storage.SaveCheckResult(u.ID, status, checkErr, c.now()); err != nil {
+		log.Error("failed to save check result", sl.Err(err))
+	}
+}
+
+func (c *Checker) checkTarget(ctx context.Context, hosts *hostLimiter, target string) (int, error) {
+	if c.opts.Policy != nil {
+		if err := c.opts.Policy.Check(ctx, target); err != nil {
+			return 0, err
+		}
+	}
+	return c.check(ctx, hosts, target)
+}
+
+// targets lists the targets the link redirects to now or later, without
+// duplicates: the current default, the scheduled ones still to come, and
+// those of rules and split variants.
+func targets(u storage.URL, now time.Time) []string {
+	current := u.URL
+	if target, ok := schedule.Target(u.Schedule, now); ok {
+		current = target
+	}
+
+	all := []string{current}
+	for _, e := range u.Schedule {
+		if e.At.After(now) {
+			all = append(all, e.Target)
+		}
+	}
+	for _, r := range u.Rules {
+		all = append(all, r.Target)
+	}
+	for _, v := range u.Split.Variants {
+		all = append(all, v.Target)
+	}
+
+	seen := make(map[string]bool, len(all))
+	out := all[:0]
+	for _, t := range all {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Check requests target with HEAD, falling back to GET for servers that
+// do not support HEAD. It returns the final status code after redirects.
+func (c *Checker) Check(ctx context.Context, target string) (int, error) {
+	return c.check(ctx, nil, target)
+}
+
+func (c *Checker) check(ctx context.Context, hosts *hostLimiter, target string) (int, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return 0, err
+	}
+
+	// Waiting for the host slot does not count towards the timeout.
+	if err := hosts.wait(ctx, u.Host); err != nil {
+		return 0, err
+	}
+
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+
+	status, err := c.do(ctx, http.MethodHead, u)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+		return status, nil
+	}
+
+	return c.do(ctx, http.MethodGet, u)
+}
+
+func (c *Checker) do(ctx context.Context, method string, u *url.URL) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+
+	return resp.StatusCode, nil
+}
+
+// hostLimiter spaces out requests to the same host. It lives for one
+// pass, so its memory is bounded by the batch size.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{
+		interval: interval,
+		next:     make(map[string]time.Time),
+	}
+}
+
+func (h *hostLimiter) wait(ctx context.Context, host string) error {
+	if h == nil || h.interval <= 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	at := h.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	h.next[host] = at.Add(h.interval)
+	h.mu.Unlock()
+
+	delay := at.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for host slot: %w", ctx.Err())
+	}
+}
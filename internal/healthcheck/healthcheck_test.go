@@ -0,0 +1,265 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+type result struct {
+	status   int
+	checkErr string
+}
+
+type fakeStorage struct {
+	mu      sync.Mutex
+	urls    []storage.URL
+	results map[int64]result
+}
+
+func (f *fakeStorage) URLsToCheck(time.Time, int) ([]storage.URL, error) {
+	return f.urls, nil
+}
+
+func (f *fakeStorage) SaveCheckResult(urlID int64, status int, checkErr string, _ time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results[urlID] = result{status: status, checkErr: checkErr}
+	return nil
+}
+
+// denyPolicy rejects every URL containing the substring.
+type denyPolicy string
+
+func (p denyPolicy) Check(_ context.Context, rawURL string) error {
+	if strings.Contains(rawURL, string(p)) {
+		return errors.New("denied")
+	}
+	return nil
+}
+
+func TestChecker_CheckDue(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		assert.Equal(t, userAgent, r.UserAgent())
+	}))
+	defer ok.Close()
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	noHead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer noHead.Close()
+
+	redirected := httptest.NewServer(http.RedirectHandler(missing.URL+"/gone", http.StatusFound))
+	defer redirected.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	st := &fakeStorage{
+		urls: []storage.URL{
+			{ID: 1, URL: ok.URL},
+			{ID: 2, URL: missing.URL + "/page"},
+			{ID: 3, URL: noHead.URL},
+			{ID: 4, URL: redirected.URL},
+			{ID: 5, URL: slow.URL},
+		},
+		results: make(map[int64]result),
+	}
+
+	c := New(slogdiscard.NewDiscardLogger(), st, http.DefaultClient, Options{
+		Timeout:     100 * time.Millisecond,
+		Concurrency: 2,
+		BatchSize:   10,
+	})
+
+	require.NoError(t, c.CheckDue(context.Background()))
+
+	assert.Equal(t, result{status: http.StatusOK}, st.results[1])
+	assert.Equal(t, result{status: http.StatusNotFound}, st.results[2])
+	assert.Equal(t, result{status: http.StatusOK}, st.results[3])
+	assert.Equal(t, result{status: http.StatusNotFound}, st.results[4])
+	assert.Equal(t, 0, st.results[5].status)
+	assert.Contains(t, st.results[5].checkErr, "deadline exceeded")
+}
+
+func TestChecker_Concurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	st := &fakeStorage{results: make(map[int64]result)}
+	for i := int64(1); i <= 10; i++ {
+		st.urls = append(st.urls, storage.URL{ID: i, URL: srv.URL})
+	}
+
+	c := New(slogdiscard.NewDiscardLogger(), st, http.DefaultClient, Options{Concurrency: 3})
+	require.NoError(t, c.CheckDue(context.Background()))
+
+	assert.Len(t, st.results, 10)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+}
+
+func TestChecker_HostInterval(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	st := &fakeStorage{
+		urls: []storage.URL{
+			{ID: 1, URL: srv.URL + "/a"},
+			{ID: 2, URL: srv.URL + "/b"},
+			{ID: 3, URL: srv.URL + "/c"},
+		},
+		results: make(map[int64]result),
+	}
+
+	const interval = 30 * time.Millisecond
+
+	c := New(slogdiscard.NewDiscardLogger(), st, http.DefaultClient, Options{
+		Concurrency:  3,
+		HostInterval: interval,
+	})
+
+	start := time.Now()
+	require.NoError(t, c.CheckDue(context.Background()))
+
+	require.Len(t, times, 3)
+	assert.GreaterOrEqual(t, time.Since(start), 2*interval)
+}
+
+func TestChecker_PolicyAppliesToRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/start", http.RedirectHandler("/internal", http.StatusFound))
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect target must not be requested")
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := New(slogdiscard.NewDiscardLogger(), nil, http.DefaultClient, Options{Policy: denyPolicy("/internal")})
+
+	_, err := c.Check(context.Background(), srv.URL+"/start")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "denied")
+
+	_, err = c.Check(context.Background(), srv.URL+"/loop")
+	assert.ErrorIs(t, err, errTooManyHops)
+}
+
+func TestChecker_CheckDueAllTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	st := &fakeStorage{
+		urls: []storage.URL{
+			{
+				ID:    1,
+				URL:   srv.URL + "/a",
+				Rules: []storage.Rule{{Platform: "ios", Target: srv.URL + "/b"}},
+				Split: storage.Split{Variants: []storage.Variant{
+					{Name: "a", Target: srv.URL + "/a", Weight: 50},
+					{Name: "b", Target: srv.URL + "/gone", Weight: 50},
+				}},
+			},
+			{
+				ID:       2,
+				URL:      srv.URL + "/gone",
+				Schedule: []storage.ScheduleEntry{{At: time.Now().Add(-time.Hour), Target: srv.URL + "/a"}},
+			},
+			{
+				ID:    3,
+				URL:   srv.URL + "/gone",
+				Rules: []storage.Rule{{Platform: "ios", Target: srv.URL + "/a"}},
+			},
+		},
+		results: make(map[int64]result),
+	}
+
+	c := New(slogdiscard.NewDiscardLogger(), st, http.DefaultClient, Options{BatchSize: 10})
+
+	require.NoError(t, c.CheckDue(context.Background()))
+
+	// A dead variant makes the link broken.
+	assert.Equal(t, result{
+		status:   http.StatusNotFound,
+		checkErr: "target " + srv.URL + "/gone: status 404",
+	}, st.results[1])
+	// The default replaced by the schedule is no longer requested.
+	assert.Equal(t, result{status: http.StatusOK}, st.results[2])
+	assert.Equal(t, result{status: http.StatusNotFound}, st.results[3])
+}
+
+func TestTargets(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	u := storage.URL{
+		URL: "https://example.com/default",
+		Schedule: []storage.ScheduleEntry{
+			{At: now.Add(-time.Hour), Target: "https://example.com/current"},
+			{At: now.Add(time.Hour), Target: "https://example.com/next"},
+		},
+		Rules: []storage.Rule{{Platform: "ios", Target: "https://example.com/ios"}},
+		Split: storage.Split{Variants: []storage.Variant{
+			{Name: "a", Target: "https://example.com/current", Weight: 50},
+			{Name: "b", Target: "https://example.com/b", Weight: 50},
+		}},
+	}
+
+	assert.Equal(t, []string{
+		"https://example.com/current",
+		"https://example.com/next",
+		"https://example.com/ios",
+		"https://example.com/b",
+	}, targets(u, now))
+}
@@ -0,0 +1,215 @@
+package apikeys
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Name   string   `json:"name" validate:"required,max=128"`
+	Scopes []string `json:"scopes"`
+}
+
+// Key never includes the key itself, which is only known on creation.
+type Key struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	OwnerID    int64      `json:"owner_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateResponse struct {
+	resp.Response
+	Key *Key `json:"key,omitempty"`
+	// Secret is the key to send in X-API-Key. It cannot be shown again.
+	Secret string `json:"secret,omitempty"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Keys []Key `json:"keys"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=APIKeySaver
+type APIKeySaver interface {
+	SaveAPIKey(k storage.APIKey) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=APIKeyLister
+type APIKeyLister interface {
+	ListAPIKeys() ([]storage.APIKey, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=APIKeyRevoker
+type APIKeyRevoker interface {
+	RevokeAPIKey(id int64, at time.Time) error
+}
+
+// NewCreate creates an API key owned by the calling admin.
+func NewCreate(log *slog.Logger, saver APIKeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikeys.NewCreate"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		ownerID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			log.Error("no user in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("unauthorized"))
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, resp.ValidationError(err.(validator.ValidationErrors)))
+			return
+		}
+
+		scopes, err := apikey.ValidateScopes(req.Scopes)
+		if err != nil {
+			log.Info("invalid scopes", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		secret, prefix, hash, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to create api key"))
+			return
+		}
+
+		k := storage.APIKey{
+			Name:      req.Name,
+			Prefix:    prefix,
+			Hash:      hash,
+			Scopes:    scopes,
+			OwnerID:   ownerID,
+			CreatedAt: time.Now().UTC(),
+		}
+		k.ID, err = saver.SaveAPIKey(k)
+		if err != nil {
+			log.Error("failed to save api key", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to create api key"))
+			return
+		}
+
+		log.Info("api key created", slog.Int64("api_key_id", k.ID), slog.Int64("user_id", ownerID), slog.Any("scopes", scopes))
+		render.JSON(w, r, CreateResponse{
+			Response: resp.OK(),
+			Key:      newKey(k),
+			Secret:   secret,
+		})
+	}
+}
+
+// NewList lists all API keys, including revoked ones.
+func NewList(log *slog.Logger, lister APIKeyLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikeys.NewList"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		keys, err := lister.ListAPIKeys()
+		if err != nil {
+			log.Error("failed to list api keys", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to list api keys"))
+			return
+		}
+
+		out := make([]Key, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, *newKey(k))
+		}
+
+		render.JSON(w, r, ListResponse{
+			Response: resp.OK(),
+			Keys:     out,
+		})
+	}
+}
+
+// NewRevoke revokes the API key with the id in the path. Requests with
+// the key fail from then on.
+func NewRevoke(log *slog.Logger, revoker APIKeyRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.apikeys.NewRevoke"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			log.Info("invalid api key id", slog.String("id", chi.URLParam(r, "id")))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid api key id"))
+			return
+		}
+
+		if err := revoker.RevokeAPIKey(id, time.Now()); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrAPIKeyNotFound):
+				log.Info("api key not found", slog.Int64("api_key_id", id))
+				render.JSON(w, r, resp.Error("api key not found"))
+			default:
+				log.Error("failed to revoke api key", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to revoke api key"))
+			}
+			return
+		}
+
+		log.Info("api key revoked", slog.Int64("api_key_id", id))
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func newKey(k storage.APIKey) *Key {
+	return &Key{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		OwnerID:    k.OwnerID,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: timePtr(k.LastUsedAt),
+		RevokedAt:  timePtr(k.RevokedAt),
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,152 @@
+package apikeys_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/apikeys"
+	"url-shortener/internal/http-server/handlers/apikeys/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestCreateHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		wantSave  bool
+		saveErr   error
+		wantError string
+	}{
+		{
+			name:     "Create",
+			body:     `{"name": "ci", "scopes": ["links:write"]}`,
+			wantSave: true,
+		},
+		{
+			name:      "Missing name",
+			body:      `{"scopes": ["links:write"]}`,
+			wantError: "поле Name является обязательным",
+		},
+		{
+			name:      "Unknown scope",
+			body:      `{"name": "ci", "scopes": ["admin"]}`,
+			wantError: `invalid scope: "admin"`,
+		},
+		{
+			name:      "Storage error",
+			body:      `{"name": "ci", "scopes": ["links:write"]}`,
+			wantSave:  true,
+			saveErr:   errors.New("unexpected error"),
+			wantError: "failed to create api key",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			saver := mocks.NewAPIKeySaver(t)
+			var saved storage.APIKey
+			if tc.wantSave {
+				saver.On("SaveAPIKey", mock.Anything).
+					Run(func(args mock.Arguments) { saved = args.Get(0).(storage.APIKey) }).
+					Return(int64(3), tc.saveErr).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/url/keys", strings.NewReader(tc.body))
+			req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{UserID: 7, Role: auth.RoleAdmin}))
+			rr := httptest.NewRecorder()
+			apikeys.NewCreate(slogdiscard.NewDiscardLogger(), saver).ServeHTTP(rr, req)
+
+			var body apikeys.CreateResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+			if tc.wantError != "" {
+				assert.Empty(t, body.Secret)
+				return
+			}
+
+			require.NotNil(t, body.Key)
+			assert.Equal(t, int64(3), body.Key.ID)
+			assert.Equal(t, "ci", body.Key.Name)
+			assert.Equal(t, []string{"links:write"}, body.Key.Scopes)
+			assert.Equal(t, int64(7), saved.OwnerID)
+			assert.Equal(t, apikey.Hash(body.Secret), saved.Hash)
+			assert.True(t, strings.HasPrefix(body.Secret, saved.Prefix))
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	revokedAt := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	lister := mocks.NewAPIKeyLister(t)
+	lister.On("ListAPIKeys").Return([]storage.APIKey{
+		{ID: 2, Name: "ci", Prefix: "usk_abcdefgh", Hash: "secret hash", Scopes: []string{"links:read"}, RevokedAt: revokedAt},
+	}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	apikeys.NewList(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/keys", nil))
+
+	assert.NotContains(t, rr.Body.String(), "secret hash")
+
+	var body apikeys.ListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Len(t, body.Keys, 1)
+	assert.Equal(t, "usk_abcdefgh", body.Keys[0].Prefix)
+	assert.Nil(t, body.Keys[0].LastUsedAt)
+	require.NotNil(t, body.Keys[0].RevokedAt)
+	assert.True(t, revokedAt.Equal(*body.Keys[0].RevokedAt))
+}
+
+func TestRevokeHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		id        string
+		revokeErr error
+		wantCode  int
+		wantError string
+	}{
+		{name: "Revoke", id: "2"},
+		{name: "Not found", id: "2", revokeErr: storage.ErrAPIKeyNotFound, wantError: "api key not found"},
+		{name: "Invalid id", id: "abc", wantCode: http.StatusBadRequest, wantError: "invalid api key id"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			revoker := mocks.NewAPIKeyRevoker(t)
+			if tc.wantCode == 0 {
+				revoker.On("RevokeAPIKey", int64(2), mock.AnythingOfType("time.Time")).Return(tc.revokeErr).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/url/keys/{id}", apikeys.NewRevoke(slogdiscard.NewDiscardLogger(), revoker))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/url/keys/"+tc.id, nil))
+
+			wantCode := tc.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			require.Equal(t, wantCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+}
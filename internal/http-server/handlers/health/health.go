@@ -0,0 +1,67 @@
+package health
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+const (
+	StatusHealthy  = "healthy"
+	StatusDegraded = "degraded"
+)
+
+// Component is the state of a dependency.
+type Component struct {
+	Healthy bool `json:"healthy"`
+	// State details Healthy, e.g. the state of a circuit breaker.
+	State string `json:"state,omitempty"`
+	// RetryAfter is the number of seconds until the component is tried
+	// again, if known.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// Check reports the state of one dependency; it must not block.
+type Check func() Component
+
+type Response struct {
+	resp.Response
+	Health     string               `json:"health"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// New reports the state of the dependencies. It answers 200 even when
+// some are unhealthy, since links keep redirecting without them; the
+// health field then says degraded.
+func New(log *slog.Logger, checks map[string]Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.New"
+
+		res := Response{
+			Response:   resp.OK(),
+			Health:     StatusHealthy,
+			Components: make(map[string]Component, len(checks)),
+		}
+		for name, check := range checks {
+			c := check()
+			res.Components[name] = c
+			if !c.Healthy {
+				res.Health = StatusDegraded
+			}
+		}
+
+		if res.Health != StatusHealthy {
+			log.Debug("service is degraded",
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.Any("components", res.Components),
+			)
+		}
+
+		render.JSON(w, r, res)
+	}
+}
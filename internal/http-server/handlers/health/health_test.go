@@ -0,0 +1,56 @@
+package health_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestHealthHandler(t *testing.T) {
+	cases := []struct {
+		name   string
+		checks map[string]health.Check
+		want   string
+	}{
+		{name: "No dependencies", want: health.StatusHealthy},
+		{
+			name: "Healthy",
+			checks: map[string]health.Check{
+				"sso": func() health.Component { return health.Component{Healthy: true, State: "closed"} },
+			},
+			want: health.StatusHealthy,
+		},
+		{
+			name: "Degraded",
+			checks: map[string]health.Check{
+				"sso": func() health.Component { return health.Component{State: "open", RetryAfter: 12} },
+				"db":  func() health.Component { return health.Component{Healthy: true} },
+			},
+			want: health.StatusDegraded,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			health.New(slogdiscard.NewDiscardLogger(), tc.checks).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body health.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.want, body.Health)
+			for name, check := range tc.checks {
+				assert.Equal(t, check(), body.Components[name])
+			}
+		})
+	}
+}
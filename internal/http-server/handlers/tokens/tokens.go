@@ -0,0 +1,250 @@
+package tokens
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type RevokeRequest struct {
+	JTI string `json:"jti" validate:"required,max=256"`
+	// UserID is the user the token was issued to, for the record.
+	UserID int64 `json:"user_id,omitempty"`
+	// ExpiresAt is the exp of the token. The entry is dropped once the
+	// verifier rejects the token as expired, i.e. after its leeway.
+	// Without it the entry is kept forever.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type CutoffRequest struct {
+	// IssuedBefore defaults to now; tokens issued in the same second
+	// are rejected too, since iat has second precision.
+	IssuedBefore *time.Time `json:"issued_before,omitempty"`
+}
+
+type RevokedToken struct {
+	JTI       string     `json:"jti"`
+	UserID    int64      `json:"user_id,omitempty"`
+	RevokedBy int64      `json:"revoked_by"`
+	RevokedAt time.Time  `json:"revoked_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type Cutoff struct {
+	UserID       int64     `json:"user_id"`
+	IssuedBefore time.Time `json:"issued_before"`
+	SetBy        int64     `json:"set_by"`
+	SetAt        time.Time `json:"set_at"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Revoked []RevokedToken `json:"revoked"`
+	Cutoffs []Cutoff       `json:"cutoffs"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TokenRevoker
+type TokenRevoker interface {
+	RevokeToken(t storage.RevokedToken) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=CutoffSetter
+type CutoffSetter interface {
+	SetTokenCutoff(c storage.TokenCutoff) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=RevocationLister
+type RevocationLister interface {
+	RevokedTokens(now time.Time) ([]storage.RevokedToken, error)
+	TokenCutoffs() ([]storage.TokenCutoff, error)
+}
+
+// maxCutoffSkew is how far in the future a cutoff may be set, which
+// would also reject tokens issued until then.
+const maxCutoffSkew = time.Minute
+
+// NewRevoke revokes a JWT by its jti claim. Requests with it fail from
+// then on.
+func NewRevoke(log *slog.Logger, revoker TokenRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tokens.NewRevoke"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		adminID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			log.Error("no user in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("unauthorized"))
+			return
+		}
+
+		var req RevokeRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.JSON(w, r, resp.ValidationError(err.(validator.ValidationErrors)))
+			return
+		}
+
+		t := storage.RevokedToken{
+			JTI:       req.JTI,
+			UserID:    req.UserID,
+			RevokedBy: adminID,
+			RevokedAt: time.Now().UTC(),
+		}
+		if req.ExpiresAt != nil {
+			t.ExpiresAt = *req.ExpiresAt
+		}
+		if err := revoker.RevokeToken(t); err != nil {
+			log.Error("failed to revoke token", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to revoke token"))
+			return
+		}
+
+		log.Info("token revoked", slog.String("jti", t.JTI), slog.Int64("user_id", t.UserID), slog.Int64("admin_id", adminID))
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// NewCutoff rejects the JWTs of the user with the id in the path issued
+// before a time, by default now.
+func NewCutoff(log *slog.Logger, setter CutoffSetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tokens.NewCutoff"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		adminID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			log.Error("no user in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("unauthorized"))
+			return
+		}
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "user_id"), 10, 64)
+		if err != nil || userID <= 0 {
+			log.Info("invalid user id", slog.String("user_id", chi.URLParam(r, "user_id")))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid user id"))
+			return
+		}
+
+		var req CutoffRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		now := time.Now().UTC()
+		issuedBefore := now.Truncate(time.Second).Add(time.Second)
+		if req.IssuedBefore != nil {
+			issuedBefore = req.IssuedBefore.UTC()
+		}
+		if issuedBefore.After(now.Add(maxCutoffSkew)) {
+			log.Info("cutoff in the future", slog.Time("issued_before", issuedBefore))
+			render.JSON(w, r, resp.Error("issued_before is in the future"))
+			return
+		}
+
+		c := storage.TokenCutoff{
+			UserID:       userID,
+			IssuedBefore: issuedBefore,
+			SetBy:        adminID,
+			SetAt:        now,
+		}
+		if err := setter.SetTokenCutoff(c); err != nil {
+			log.Error("failed to set token cutoff", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to revoke tokens"))
+			return
+		}
+
+		log.Info("tokens issued before cutoff revoked",
+			slog.Int64("user_id", userID),
+			slog.Time("issued_before", issuedBefore),
+			slog.Int64("admin_id", adminID),
+		)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// NewList lists the revoked tokens that have not expired and the
+// cutoffs.
+func NewList(log *slog.Logger, lister RevocationLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tokens.NewList"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		revoked, err := lister.RevokedTokens(time.Now())
+		if err != nil {
+			log.Error("failed to list revoked tokens", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to list revoked tokens"))
+			return
+		}
+		cutoffs, err := lister.TokenCutoffs()
+		if err != nil {
+			log.Error("failed to list token cutoffs", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to list revoked tokens"))
+			return
+		}
+
+		res := ListResponse{
+			Response: resp.OK(),
+			Revoked:  make([]RevokedToken, 0, len(revoked)),
+			Cutoffs:  make([]Cutoff, 0, len(cutoffs)),
+		}
+		for _, t := range revoked {
+			res.Revoked = append(res.Revoked, RevokedToken{
+				JTI:       t.JTI,
+				UserID:    t.UserID,
+				RevokedBy: t.RevokedBy,
+				RevokedAt: t.RevokedAt,
+				ExpiresAt: timePtr(t.ExpiresAt),
+			})
+		}
+		for _, c := range cutoffs {
+			res.Cutoffs = append(res.Cutoffs, Cutoff{
+				UserID:       c.UserID,
+				IssuedBefore: c.IssuedBefore,
+				SetBy:        c.SetBy,
+				SetAt:        c.SetAt,
+			})
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
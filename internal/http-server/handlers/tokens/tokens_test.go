@@ -0,0 +1,181 @@
+package tokens_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/tokens"
+	"url-shortener/internal/http-server/handlers/tokens/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func asAdmin(r *http.Request) *http.Request {
+	return r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: 1, Role: auth.RoleAdmin}))
+}
+
+func TestRevokeHandler(t *testing.T) {
+	expiresAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		body       string
+		wantRevoke bool
+		revokeErr  error
+		wantError  string
+	}{
+		{
+			name:       "Revoke",
+			body:       `{"jti": "abc", "user_id": 7, "expires_at": "2026-01-01T12:00:00Z"}`,
+			wantRevoke: true,
+		},
+		{
+			name:      "Missing jti",
+			body:      `{"user_id": 7}`,
+			wantError: "поле JTI является обязательным",
+		},
+		{
+			name:       "Storage error",
+			body:       `{"jti": "abc"}`,
+			wantRevoke: true,
+			revokeErr:  errors.New("unexpected error"),
+			wantError:  "failed to revoke token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			revoker := mocks.NewTokenRevoker(t)
+			var revoked storage.RevokedToken
+			if tc.wantRevoke {
+				revoker.On("RevokeToken", mock.Anything).
+					Run(func(args mock.Arguments) { revoked = args.Get(0).(storage.RevokedToken) }).
+					Return(tc.revokeErr).Once()
+			}
+
+			req := asAdmin(httptest.NewRequest(http.MethodPost, "/url/tokens/revoked", strings.NewReader(tc.body)))
+			rr := httptest.NewRecorder()
+			tokens.NewRevoke(slogdiscard.NewDiscardLogger(), revoker).ServeHTTP(rr, req)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+			if tc.wantError != "" {
+				return
+			}
+
+			assert.Equal(t, "abc", revoked.JTI)
+			assert.Equal(t, int64(7), revoked.UserID)
+			assert.Equal(t, int64(1), revoked.RevokedBy)
+			assert.True(t, expiresAt.Equal(revoked.ExpiresAt))
+		})
+	}
+}
+
+func TestCutoffHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		userID    string
+		body      string
+		wantSet   bool
+		wantAt    time.Time
+		wantCode  int
+		wantError string
+	}{
+		{name: "Now", userID: "7", wantSet: true},
+		{
+			name:    "Given time",
+			userID:  "7",
+			body:    `{"issued_before": "2026-01-01T12:00:00Z"}`,
+			wantSet: true,
+			wantAt:  time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "Future",
+			userID:    "7",
+			body:      `{"issued_before": "2999-01-01T00:00:00Z"}`,
+			wantError: "issued_before is in the future",
+		},
+		{name: "Invalid user id", userID: "abc", wantCode: http.StatusBadRequest, wantError: "invalid user id"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setter := mocks.NewCutoffSetter(t)
+			var set storage.TokenCutoff
+			if tc.wantSet {
+				setter.On("SetTokenCutoff", mock.Anything).
+					Run(func(args mock.Arguments) { set = args.Get(0).(storage.TokenCutoff) }).
+					Return(nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Put("/url/tokens/cutoffs/{user_id}", tokens.NewCutoff(slogdiscard.NewDiscardLogger(), setter))
+
+			before := time.Now()
+			req := asAdmin(httptest.NewRequest(http.MethodPut, "/url/tokens/cutoffs/"+tc.userID, strings.NewReader(tc.body)))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			wantCode := tc.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			require.Equal(t, wantCode, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+			if tc.wantError != "" {
+				return
+			}
+
+			assert.Equal(t, int64(7), set.UserID)
+			assert.Equal(t, int64(1), set.SetBy)
+			if tc.wantAt.IsZero() {
+				// Tokens issued in the current second are rejected too.
+				assert.True(t, set.IssuedBefore.After(before))
+				assert.Zero(t, set.IssuedBefore.Nanosecond())
+				return
+			}
+			assert.True(t, tc.wantAt.Equal(set.IssuedBefore))
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	lister := mocks.NewRevocationLister(t)
+	lister.On("RevokedTokens", mock.AnythingOfType("time.Time")).Return([]storage.RevokedToken{
+		{JTI: "abc", UserID: 7, RevokedBy: 1, RevokedAt: at},
+	}, nil).Once()
+	lister.On("TokenCutoffs").Return([]storage.TokenCutoff{
+		{UserID: 8, IssuedBefore: at, SetBy: 1, SetAt: at},
+	}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	tokens.NewList(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/tokens/revoked", nil))
+
+	var body tokens.ListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Len(t, body.Revoked, 1)
+	assert.Equal(t, "abc", body.Revoked[0].JTI)
+	assert.Nil(t, body.Revoked[0].ExpiresAt)
+	require.Len(t, body.Cutoffs, 1)
+	assert.Equal(t, int64(8), body.Cutoffs[0].UserID)
+	assert.True(t, at.Equal(body.Cutoffs[0].IssuedBefore))
+}
@@ -0,0 +1,87 @@
+package broken
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=BrokenURLLister
+type BrokenURLLister interface {
+	BrokenURLs(limit int) ([]storage.URL, error)
+}
+
+type Link struct {
+	Domain    string    `json:"domain"`
+	Alias     string    `json:"alias"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Response struct {
+	resp.Response
+	Links []Link `json:"links"`
+}
+
+// New lists links whose last health check failed, most recently checked
+// first.
+func New(log *slog.Logger, lister BrokenURLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.broken.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit := defaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxLimit {
+				log.Info("invalid limit", slog.String("limit", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("limit must be between 1 and "+strconv.Itoa(maxLimit)))
+				return
+			}
+			limit = n
+		}
+
+		urls, err := lister.BrokenURLs(limit)
+		if err != nil {
+			log.Error("failed to list broken urls", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to list broken urls"))
+			return
+		}
+
+		links := make([]Link, 0, len(urls))
+		for _, u := range urls {
+			links = append(links, Link{
+				Domain:    u.Domain,
+				Alias:     u.Alias,
+				URL:       u.URL,
+				Status:    u.LastStatus,
+				Error:     u.LastCheckError,
+				CheckedAt: u.LastCheckedAt,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Links:    links,
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package broken_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/broken"
+	"url-shortener/internal/http-server/handlers/url/broken/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestBrokenHandler(t *testing.T) {
+	checkedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		query     string
+		limit     int
+		urls      []storage.URL
+		listErr   error
+		wantCode  int
+		wantError string
+		wantLinks []broken.Link
+	}{
+		{
+			name:  "Success",
+			limit: 100,
+			urls: []storage.URL{
+				{Domain: "go.example", Alias: "gone", URL: "https://example.com/gone", LastStatus: 404, LastCheckedAt: checkedAt},
+				{Domain: "go.example", Alias: "down", URL: "https://down.example", LastCheckError: "connection refused", LastCheckedAt: checkedAt},
+			},
+			wantCode: http.StatusOK,
+			wantLinks: []broken.Link{
+				{Domain: "go.example", Alias: "gone", URL: "https://example.com/gone", Status: 404, CheckedAt: checkedAt},
+				{Domain: "go.example", Alias: "down", URL: "https://down.example", Error: "connection refused", CheckedAt: checkedAt},
+			},
+		},
+		{
+			name:      "Empty",
+			query:     "limit=5",
+			limit:     5,
+			wantCode:  http.StatusOK,
+			wantLinks: []broken.Link{},
+		},
+		{
+			name:      "Invalid limit",
+			query:     "limit=5000",
+			wantCode:  http.StatusBadRequest,
+			wantError: "limit must be between 1 and 1000",
+		},
+		{
+			name:      "Storage error",
+			limit:     100,
+			listErr:   errors.New("unexpected error"),
+			wantCode:  http.StatusOK,
+			wantError: "failed to list broken urls",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := mocks.NewBrokenURLLister(t)
+			if tc.limit > 0 {
+				lister.On("BrokenURLs", tc.limit).Return(tc.urls, tc.listErr).Once()
+			}
+
+			handler := broken.New(slogdiscard.NewDiscardLogger(), lister)
+
+			req := httptest.NewRequest(http.MethodGet, "/url/broken?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			var body broken.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			if tc.wantError != "" {
+				assert.Equal(t, resp.StatusError, body.Status)
+				assert.Equal(t, tc.wantError, body.Error)
+				return
+			}
+
+			assert.Equal(t, resp.StatusOK, body.Status)
+			assert.Equal(t, tc.wantLinks, body.Links)
+		})
+	}
+}
@@ -9,14 +9,15 @@ import (
 	"github.com/go-chi/render"
 
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
 )
 
 type URLDeleter interface {
-	DeleteURL(alias string) error
+	DeleteURL(domain string, alias string) error
 }
 
 //go:generate go run github.com/vektra/mockery/v2@latest --name=URLDeleter
-func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
+func New(log *slog.Logger, urlDeleter URLDeleter, allowedDomains *domains.Set) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete.New"
 		log = log.With(
@@ -31,12 +32,22 @@ func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 			return
 		}
 
-		if err := urlDeleter.DeleteURL(alias); err != nil {
+		domain := allowedDomains.Default()
+		if d := r.URL.Query().Get("domain"); d != "" {
+			if !allowedDomains.IsAllowed(d) {
+				log.Info("domain is not allowed", slog.String("domain", d))
+				render.JSON(w, r, resp.Error("domain is not allowed"))
+				return
+			}
+			domain = domains.Normalize(d)
+		}
+
+		if err := urlDeleter.DeleteURL(domain, alias); err != nil {
 			render.JSON(w, r, resp.Error("failed to delete url"))
 			return
 		}
 
-		log.Info("url deleted", slog.String("alias", alias))
+		log.Info("url deleted", slog.String("alias", alias), slog.String("domain", domain))
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -0,0 +1,185 @@
+package details
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
+type URLGetter interface {
+	GetURL(domain string, alias string) (storage.URL, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=VariantCounter
+type VariantCounter interface {
+	CountClicksByVariant(urlID int64) (map[string]int64, error)
+}
+
+type Metadata struct {
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Image       string     `json:"image,omitempty"`
+	Favicon     string     `json:"favicon,omitempty"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+}
+
+type Health struct {
+	Status    int        `json:"status,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Broken    bool       `json:"broken"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+type Link struct {
+	Domain          string                  `json:"domain"`
+	Alias           string                  `json:"alias"`
+	URL             string                  `json:"url"`
+	RedirectType    int                     `json:"redirect_type"`
+	ForwardQuery    bool                    `json:"forward_query"`
+	QueryPrecedence string                  `json:"query_precedence"`
+	ForwardPath     bool                    `json:"forward_path"`
+	Title           string                  `json:"title,omitempty"`
+	Notes           string                  `json:"notes,omitempty"`
+	Protected       bool                    `json:"protected"`
+	CreatedAt       time.Time               `json:"created_at"`
+	OwnerID         int64                   `json:"owner_id,omitempty"`
+	Metadata        Metadata                `json:"metadata"`
+	Health          Health                  `json:"health"`
+	Rules           []storage.Rule          `json:"rules,omitempty"`
+	Split           *storage.Split          `json:"split,omitempty"`
+	NotBefore       *time.Time              `json:"not_before,omitempty"`
+	Schedule        []storage.ScheduleEntry `json:"schedule,omitempty"`
+	Tags            []string                `json:"tags,omitempty"`
+	// CustomMetadata is the metadata object the link was saved with;
+	// Metadata above describes the target page.
+	CustomMetadata map[string]json.RawMessage `json:"custom_metadata,omitempty"`
+	// VariantClicks counts clicks per split variant.
+	VariantClicks map[string]int64 `json:"variant_clicks,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Link *Link `json:"link,omitempty"`
+}
+
+// New returns everything known about a link, including the fetched page
+// metadata and the last health check.
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	variantCounter VariantCounter,
+	allowedDomains *domains.Set,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.details.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is empty"))
+			return
+		}
+
+		domain := allowedDomains.Default()
+		if d := r.URL.Query().Get("domain"); d != "" {
+			if !allowedDomains.IsAllowed(d) {
+				log.Info("domain is not allowed", slog.String("domain", d))
+				render.JSON(w, r, resp.Error("domain is not allowed"))
+				return
+			}
+			domain = domains.Normalize(d)
+		}
+
+		u, err := urlGetter.GetURL(domain, alias)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to get url", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to get url"))
+			}
+			return
+		}
+
+		link := NewLink(u)
+		if len(u.Split.Variants) > 0 {
+			link.VariantClicks, err = variantCounter.CountClicksByVariant(u.ID)
+			if err != nil {
+				log.Error("failed to count clicks", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to get url"))
+				return
+			}
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Link:     link,
+		})
+	}
+}
+
+// NewLink converts a stored link for API responses.
+func NewLink(u storage.URL) *Link {
+	link := &Link{
+		Domain:          u.Domain,
+		Alias:           u.Alias,
+		URL:             u.URL,
+		RedirectType:    u.RedirectType,
+		ForwardQuery:    u.ForwardQuery,
+		QueryPrecedence: u.QueryPrecedence,
+		ForwardPath:     u.ForwardPath,
+		Title:           u.Title,
+		Notes:           u.Notes,
+		Protected:       u.PasswordHash != "",
+		CreatedAt:       u.CreatedAt,
+		OwnerID:         u.OwnerID,
+		Metadata: Metadata{
+			Title:       u.Meta.Title,
+			Description: u.Meta.Description,
+			Image:       u.Meta.Image,
+			Favicon:     u.Meta.Favicon,
+			FetchedAt:   timePtr(u.Meta.FetchedAt),
+		},
+		Health: Health{
+			Status:    u.LastStatus,
+			Error:     u.LastCheckError,
+			Broken:    u.IsBroken(),
+			CheckedAt: timePtr(u.LastCheckedAt),
+		},
+		Rules:          u.Rules,
+		NotBefore:      timePtr(u.NotBefore),
+		Schedule:       u.Schedule,
+		Tags:           u.Tags,
+		CustomMetadata: u.Metadata,
+	}
+	if len(u.Split.Variants) > 0 {
+		link.Split = &u.Split
+	}
+
+	return link
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
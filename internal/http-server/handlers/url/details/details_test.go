@@ -0,0 +1,162 @@
+package details_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/details"
+	"url-shortener/internal/http-server/handlers/url/details/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestDetailsHandler(t *testing.T) {
+	fetchedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		query     string
+		domain    string
+		url       storage.URL
+		getErr    error
+		wantError string
+		check     func(t *testing.T, link *details.Link)
+	}{
+		{
+			name:   "With metadata",
+			domain: "go.example",
+			url: storage.URL{
+				Domain:       "go.example",
+				Alias:        "docs",
+				URL:          "https://example.com/docs",
+				RedirectType: http.StatusFound,
+				PasswordHash: "hash",
+				Meta: storage.PageMeta{
+					Title:       "Docs",
+					Description: "All the docs",
+					Image:       "https://example.com/og.png",
+					Favicon:     "https://example.com/favicon.ico",
+					FetchedAt:   fetchedAt,
+				},
+			},
+			check: func(t *testing.T, link *details.Link) {
+				assert.Equal(t, "https://example.com/docs", link.URL)
+				assert.True(t, link.Protected)
+				assert.Equal(t, "Docs", link.Metadata.Title)
+				assert.Equal(t, "All the docs", link.Metadata.Description)
+				assert.Equal(t, "https://example.com/og.png", link.Metadata.Image)
+				assert.Equal(t, "https://example.com/favicon.ico", link.Metadata.Favicon)
+				require.NotNil(t, link.Metadata.FetchedAt)
+				assert.True(t, fetchedAt.Equal(*link.Metadata.FetchedAt))
+				assert.Nil(t, link.Health.CheckedAt)
+			},
+		},
+		{
+			name:   "Other domain without metadata",
+			query:  "domain=s.example",
+			domain: "s.example",
+			url:    storage.URL{Domain: "s.example", Alias: "docs", URL: "https://example.com/other"},
+			check: func(t *testing.T, link *details.Link) {
+				assert.Equal(t, "s.example", link.Domain)
+				assert.Empty(t, link.Metadata.Title)
+				assert.Nil(t, link.Metadata.FetchedAt)
+			},
+		},
+		{
+			name:   "With split",
+			domain: "go.example",
+			url: storage.URL{
+				ID:     7,
+				Domain: "go.example",
+				Alias:  "docs",
+				URL:    "https://example.com/docs",
+				Split: storage.Split{Variants: []storage.Variant{
+					{Name: "a", Target: "https://example.com/a", Weight: 1},
+					{Name: "b", Target: "https://example.com/b", Weight: 1},
+				}},
+			},
+			check: func(t *testing.T, link *details.Link) {
+				require.NotNil(t, link.Split)
+				assert.Len(t, link.Split.Variants, 2)
+				assert.Equal(t, map[string]int64{"a": 3, "b": 1}, link.VariantClicks)
+			},
+		},
+		{
+			name:   "With tags and custom metadata",
+			domain: "go.example",
+			url: storage.URL{
+				Domain:   "go.example",
+				Alias:    "docs",
+				URL:      "https://example.com/docs",
+				Tags:     []string{"docs", "team:growth"},
+				Metadata: map[string]json.RawMessage{"owner": json.RawMessage(`"growth"`)},
+			},
+			check: func(t *testing.T, link *details.Link) {
+				assert.Equal(t, []string{"docs", "team:growth"}, link.Tags)
+				assert.JSONEq(t, `"growth"`, string(link.CustomMetadata["owner"]))
+			},
+		},
+		{
+			name:      "Not found",
+			domain:    "go.example",
+			getErr:    storage.ErrURLNotFound,
+			wantError: "url not found",
+		},
+		{
+			name:      "Domain not allowed",
+			query:     "domain=evil.example",
+			wantError: "domain is not allowed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetter := mocks.NewURLGetter(t)
+			if tc.domain != "" {
+				urlGetter.On("GetURL", tc.domain, "docs").Return(tc.url, tc.getErr).Once()
+			}
+
+			r := chi.NewRouter()
+			variantCounter := mocks.NewVariantCounter(t)
+			if len(tc.url.Split.Variants) > 0 {
+				variantCounter.On("CountClicksByVariant", tc.url.ID).
+					Return(map[string]int64{"a": 3, "b": 1}, nil).Once()
+			}
+
+			r.Get("/url/{alias}", details.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetter,
+				variantCounter,
+				domains.New("go.example", []string{"s.example"}),
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/docs?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body details.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+
+			if tc.wantError != "" {
+				assert.Equal(t, resp.StatusError, body.Status)
+				assert.Equal(t, tc.wantError, body.Error)
+				return
+			}
+
+			require.Equal(t, resp.StatusOK, body.Status)
+			require.NotNil(t, body.Link)
+			tc.check(t, body.Link)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/handlers/url/details"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/storage"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+
+	// pageSize is how many links are loaded from storage at a time.
+	pageSize = 500
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLLister
+type URLLister interface {
+	ListURLs(filter storage.ListFilter) ([]storage.URL, error)
+}
+
+var csvHeader = []string{
+	"domain", "alias", "url", "redirect_type", "title", "tags", "metadata", "created_at",
+}
+
+// New streams all links, or all links with ?tag=, as a JSON array or,
+// with ?format=csv, as CSV. In CSV tags are separated by spaces,
+// metadata is a JSON object and cells that would start a spreadsheet
+// formula are prefixed with a quote.
+func New(log *slog.Logger, lister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.export.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = FormatJSON
+		}
+		if format != FormatJSON && format != FormatCSV {
+			log.Info("invalid format", slog.String("format", format))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("format must be json or csv"))
+			return
+		}
+
+		filter := storage.ListFilter{Limit: pageSize}
+		if v := query.Get("tag"); v != "" {
+			t, err := tags.Normalize([]string{v})
+			if err != nil {
+				log.Info("invalid tag", slog.String("tag", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(err.Error()))
+				return
+			}
+			filter.Tag = t[0]
+		}
+
+		// The first page is loaded before writing anything, so that
+		// storage errors can still be reported properly.
+		urls, err := lister.ListURLs(filter)
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to export urls"))
+			return
+		}
+
+		var enc encoder
+		if format == FormatCSV {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			enc = newCSVEncoder(w)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			enc = &jsonEncoder{w: w}
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="links.`+format+`"`)
+
+		count := 0
+		for {
+			for _, u := range urls {
+				if err := enc.encode(u); err != nil {
+					log.Error("failed to write export", sl.Err(err))
+					return
+				}
+			}
+			count += len(urls)
+
+			if len(urls) < pageSize {
+				break
+			}
+			// Links created meanwhile shift the pages, which at worst
+			// repeats a link in the export.
+			filter.Offset += pageSize
+			urls, err = lister.ListURLs(filter)
+			if err != nil {
+				// Headers are sent, the truncated body is all that is left.
+				log.Error("failed to list urls", sl.Err(err))
+				return
+			}
+		}
+
+		if err := enc.close(); err != nil {
+			log.Error("failed to write export", sl.Err(err))
+			return
+		}
+
+		log.Info("urls exported", slog.String("format", format), slog.String("tag", filter.Tag), slog.Int("count", count))
+	}
+}
+
+type encoder interface {
+	encode(u storage.URL) error
+	close() error
+}
+
+type jsonEncoder struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (e *jsonEncoder) encode(u storage.URL) error {
+	sep := ","
+	if !e.started {
+		sep = "["
+		e.started = true
+	}
+
+	b, err := json.Marshal(details.NewLink(u))
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append([]byte(sep), b...))
+	return err
+}
+
+func (e *jsonEncoder) close() error {
+	end := "]"
+	if !e.started {
+		end = "[]"
+	}
+	_, err := e.w.Write([]byte(end + "\n"))
+	return err
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w http.ResponseWriter) *csvEncoder {
+	e := &csvEncoder{w: csv.NewWriter(w)}
+	_ = e.w.Write(csvHeader)
+	return e
+}
+
+func (e *csvEncoder) encode(u storage.URL) error {
+	metadata := ""
+	if len(u.Metadata) > 0 {
+		b, err := json.Marshal(u.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(b)
+	}
+
+	createdAt := ""
+	if !u.CreatedAt.IsZero() {
+		createdAt = u.CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	record := []string{
+		u.Domain,
+		u.Alias,
+		u.URL,
+		strconv.Itoa(u.RedirectType),
+		u.Title,
+		strings.Join(u.Tags, " "),
+		metadata,
+		createdAt,
+	}
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+
+	return e.w.Write(record)
+}
+
+// escapeFormula keeps spreadsheets from running cells as formulas by
+// prefixing the characters that start one with a quote.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
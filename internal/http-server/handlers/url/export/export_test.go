@@ -0,0 +1,148 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/details"
+	"url-shortener/internal/http-server/handlers/url/export/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestExportHandler_JSONPages(t *testing.T) {
+	first := make([]storage.URL, pageSize)
+	for i := range first {
+		first[i] = storage.URL{Domain: "go.example", Alias: fmt.Sprintf("a%d", i), URL: "https://example.com"}
+	}
+	second := []storage.URL{{Domain: "go.example", Alias: "last", URL: "https://example.com", Tags: []string{"docs"}}}
+
+	lister := mocks.NewURLLister(t)
+	lister.On("ListURLs", storage.ListFilter{Tag: "docs", Limit: pageSize}).Return(first, nil).Once()
+	lister.On("ListURLs", storage.ListFilter{Tag: "docs", Limit: pageSize, Offset: pageSize}).Return(second, nil).Once()
+
+	rr := httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/export?tag=docs", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `attachment; filename="links.json"`, rr.Header().Get("Content-Disposition"))
+
+	var links []details.Link
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &links))
+	require.Len(t, links, pageSize+1)
+	assert.Equal(t, "a0", links[0].Alias)
+	assert.Equal(t, []string{"docs"}, links[pageSize].Tags)
+}
+
+func TestExportHandler_EmptyJSON(t *testing.T) {
+	lister := mocks.NewURLLister(t)
+	lister.On("ListURLs", storage.ListFilter{Limit: pageSize}).Return(nil, nil).Once()
+
+	rr := httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/export", nil))
+
+	assert.Equal(t, "[]\n", rr.Body.String())
+}
+
+func TestExportHandler_CSV(t *testing.T) {
+	createdAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	lister := mocks.NewURLLister(t)
+	lister.On("ListURLs", storage.ListFilter{Limit: pageSize}).Return([]storage.URL{{
+		Domain:       "go.example",
+		Alias:        "docs",
+		URL:          "https://example.com/docs",
+		RedirectType: http.StatusFound,
+		Title:        "Docs, all of them",
+		Tags:         []string{"docs", "team:growth"},
+		Metadata:     map[string]json.RawMessage{"owner": json.RawMessage(`"growth"`)},
+		CreatedAt:    createdAt,
+	}}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/export?format=csv", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		csvHeader,
+		{
+			"go.example", "docs", "https://example.com/docs", "302", "Docs, all of them",
+			"docs team:growth", `{"owner":"growth"}`, "2026-03-04T05:06:07Z",
+		},
+	}, records)
+}
+
+func TestExportHandler_CSVFormulas(t *testing.T) {
+	lister := mocks.NewURLLister(t)
+	lister.On("ListURLs", storage.ListFilter{Limit: pageSize}).Return([]storage.URL{{
+		Domain: "go.example",
+		Alias:  "-calc",
+		URL:    "https://example.com/docs",
+		Title:  `=HYPERLINK("https://evil.example","click")`,
+		Tags:   []string{"+1", "docs"},
+	}}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/export?format=csv", nil))
+
+	records, err := csv.NewReader(strings.NewReader(rr.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{
+		"go.example", "'-calc", "https://example.com/docs", "0", `'=HYPERLINK("https://evil.example","click")`,
+		"'+1 docs", "", "",
+	}, records[1])
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{cell: "", want: ""},
+		{cell: "docs", want: "docs"},
+		{cell: "a=b", want: "a=b"},
+		{cell: "=1+2", want: "'=1+2"},
+		{cell: "+1", want: "'+1"},
+		{cell: "-1", want: "'-1"},
+		{cell: "@SUM(A1)", want: "'@SUM(A1)"},
+		{cell: "\t=1", want: "'\t=1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, escapeFormula(tt.cell), tt.cell)
+	}
+}
+
+func TestExportHandler_Errors(t *testing.T) {
+	rr := httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), mocks.NewURLLister(t)).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/export?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	lister := mocks.NewURLLister(t)
+	lister.On("ListURLs", storage.ListFilter{Limit: pageSize}).Return(nil, errors.New("unexpected error")).Once()
+
+	rr = httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/export", nil))
+	assert.Contains(t, rr.Body.String(), "failed to export urls")
+}
@@ -0,0 +1,96 @@
+package list
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/handlers/url/details"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLLister
+type URLLister interface {
+	ListURLs(filter storage.ListFilter) ([]storage.URL, error)
+}
+
+type Response struct {
+	resp.Response
+	Links []*details.Link `json:"links"`
+}
+
+// New lists links, newest first. ?tag= only lists links with that tag,
+// ?limit= and ?offset= page through the result.
+func New(log *slog.Logger, lister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+		filter := storage.ListFilter{Limit: defaultLimit}
+
+		if v := query.Get("tag"); v != "" {
+			t, err := tags.Normalize([]string{v})
+			if err != nil {
+				log.Info("invalid tag", slog.String("tag", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error(err.Error()))
+				return
+			}
+			filter.Tag = t[0]
+		}
+
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxLimit {
+				log.Info("invalid limit", slog.String("limit", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("limit must be between 1 and "+strconv.Itoa(maxLimit)))
+				return
+			}
+			filter.Limit = n
+		}
+
+		if v := query.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.Info("invalid offset", slog.String("offset", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("offset must not be negative"))
+				return
+			}
+			filter.Offset = n
+		}
+
+		urls, err := lister.ListURLs(filter)
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to list urls"))
+			return
+		}
+
+		links := make([]*details.Link, 0, len(urls))
+		for _, u := range urls {
+			links = append(links, details.NewLink(u))
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Links:    links,
+		})
+	}
+}
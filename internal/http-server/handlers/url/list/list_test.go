@@ -0,0 +1,94 @@
+package list_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/list/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestListHandler(t *testing.T) {
+	urls := []storage.URL{{Domain: "go.example", Alias: "docs", URL: "https://example.com/docs", Tags: []string{"docs"}}}
+
+	cases := []struct {
+		name      string
+		query     string
+		filter    *storage.ListFilter
+		listErr   error
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:   "Defaults",
+			filter: &storage.ListFilter{Limit: 100},
+		},
+		{
+			name:   "Tag and paging",
+			query:  "tag=Docs&limit=10&offset=20",
+			filter: &storage.ListFilter{Tag: "docs", Limit: 10, Offset: 20},
+		},
+		{
+			name:      "Invalid limit",
+			query:     "limit=0",
+			wantCode:  http.StatusBadRequest,
+			wantError: "limit must be between 1 and 1000",
+		},
+		{
+			name:      "Invalid offset",
+			query:     "offset=-1",
+			wantCode:  http.StatusBadRequest,
+			wantError: "offset must not be negative",
+		},
+		{
+			name:      "Invalid tag",
+			query:     "tag=a%20b",
+			wantCode:  http.StatusBadRequest,
+			wantError: `invalid tag: "a b"`,
+		},
+		{
+			name:      "Storage error",
+			filter:    &storage.ListFilter{Limit: 100},
+			listErr:   errors.New("unexpected error"),
+			wantError: "failed to list urls",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := mocks.NewURLLister(t)
+			if tc.filter != nil {
+				lister.On("ListURLs", *tc.filter).Return(urls, tc.listErr).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			list.New(slogdiscard.NewDiscardLogger(), lister).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/?"+tc.query, nil))
+
+			wantCode := tc.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			require.Equal(t, wantCode, rr.Code)
+
+			var body list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, resp.StatusOK, body.Status)
+				require.Len(t, body.Links, 1)
+				assert.Equal(t, "docs", body.Links[0].Alias)
+				assert.Equal(t, []string{"docs"}, body.Links[0].Tags)
+			}
+		})
+	}
+}
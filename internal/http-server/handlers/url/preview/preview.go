@@ -0,0 +1,136 @@
+package preview
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/schedule"
+	"url-shortener/internal/storage"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var page = template.Must(template.ParseFS(templates, "templates/preview.html"))
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
+type URLGetter interface {
+	GetURL(domain string, alias string) (storage.URL, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=ClickCounter
+type ClickCounter interface {
+	CountClicks(urlID int64) (int64, error)
+}
+
+type pageData struct {
+	Alias     string
+	URL       string
+	Title     string
+	CreatedAt time.Time
+	Clicks    int64
+	// Protected hides the target of password protected links.
+	Protected bool
+}
+
+// New renders a page describing where a short link leads. It is served
+// for /{alias}+ and ?preview=1 and is not counted as a click.
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	clickCounter ClickCounter,
+	allowedDomains *domains.Set,
+	clock func() time.Time,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.preview.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is empty"))
+			return
+		}
+
+		domain := allowedDomains.Resolve(r.Host)
+
+		u, err := urlGetter.GetURL(domain, alias)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to get url", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to get url"))
+			}
+			return
+		}
+
+		// Scheduled links stay hidden until they go live.
+		now := clock()
+		if now.Before(u.NotBefore) {
+			log.Info("url is not active yet", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("url not found"))
+			return
+		}
+		if target, ok := schedule.Target(u.Schedule, now); ok {
+			u.URL = target
+		}
+
+		clicks, err := clickCounter.CountClicks(u.ID)
+		if err != nil {
+			log.Error("failed to count clicks", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to get url"))
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := page.Execute(&buf, pageData{
+			Alias:     u.Alias,
+			URL:       u.URL,
+			Title:     u.Title,
+			CreatedAt: u.CreatedAt,
+			Clicks:    clicks,
+			Protected: u.PasswordHash != "",
+		}); err != nil {
+			log.Error("failed to render preview", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to render preview"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+// OnQuery serves preview instead of the wrapped handler when the request
+// has ?preview=1.
+func OnQuery(preview http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("preview") == "1" {
+				preview.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
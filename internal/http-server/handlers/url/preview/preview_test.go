@@ -0,0 +1,131 @@
+package preview_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/preview"
+	"url-shortener/internal/http-server/handlers/url/preview/mocks"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "Plus suffix",
+			path: "/docs+",
+		},
+		{
+			name: "Preview query",
+			path: "/docs?preview=1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", "go.example", "docs").
+				Return(storage.URL{
+					ID:        7,
+					Alias:     "docs",
+					URL:       "https://example.com/docs?a=1&b=2",
+					Title:     "Team <docs>",
+					CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC),
+				}, nil).Once()
+
+			clickCounterMock := mocks.NewClickCounter(t)
+			clickCounterMock.On("CountClicks", int64(7)).Return(int64(42), nil).Once()
+
+			previewHandler := preview.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickCounterMock,
+				domains.New("go.example", nil),
+				time.Now,
+			)
+
+			redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Fatal("redirect must not be called for preview")
+			})
+
+			r := chi.NewRouter()
+			r.Get("/{alias}+", previewHandler)
+			r.With(preview.OnQuery(previewHandler)).Get("/{alias}", redirect)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+
+			body := rr.Body.String()
+			assert.Contains(t, body, "https://example.com/docs?a=1&amp;b=2")
+			assert.Contains(t, body, "Team &lt;docs&gt;")
+			assert.Contains(t, body, "2026-03-01 12:30 UTC")
+			assert.Contains(t, body, "<dd>42</dd>")
+		})
+	}
+}
+
+func TestPreviewHandler_Schedule(t *testing.T) {
+	switchAt := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		now       time.Time
+		wantError bool
+		wantURL   string
+	}{
+		{name: "Not active yet", now: switchAt.Add(-48 * time.Hour), wantError: true},
+		{name: "Before switch", now: switchAt.Add(-time.Hour), wantURL: "https://example.com/current"},
+		{name: "After switch", now: switchAt.Add(time.Hour), wantURL: "https://example.com/archive"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", "go.example", "docs").
+				Return(storage.URL{
+					ID:        7,
+					Alias:     "docs",
+					URL:       "https://example.com/current",
+					NotBefore: switchAt.Add(-24 * time.Hour),
+					Schedule:  []storage.ScheduleEntry{{At: switchAt, Target: "https://example.com/archive"}},
+				}, nil).Once()
+
+			clickCounterMock := mocks.NewClickCounter(t)
+			if !tc.wantError {
+				clickCounterMock.On("CountClicks", int64(7)).Return(int64(0), nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}+", preview.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickCounterMock,
+				domains.New("go.example", nil),
+				func() time.Time { return tc.now },
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs+", nil))
+
+			if tc.wantError {
+				assert.Contains(t, rr.Body.String(), "url not found")
+				return
+			}
+			assert.Contains(t, rr.Body.String(), tc.wantURL)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package qr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	qrcode "github.com/skip2/go-qrcode"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	libqr "url-shortener/internal/lib/qr"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultSize   = 256
+	minSize       = 32
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 32
+
+	formatPNG = "png"
+	formatSVG = "svg"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
+type URLGetter interface {
+	GetURL(domain string, alias string) (storage.URL, error)
+}
+
+// New serves a QR code for the short link. The code encodes the public
+// short URL built from publicBaseURL, not the address the server listens on.
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	allowedDomains *domains.Set,
+	publicBaseURL *url.URL,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.qr.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is empty"))
+			return
+		}
+
+		query := r.URL.Query()
+
+		domain := allowedDomains.Default()
+		if d := query.Get("domain"); d != "" {
+			if !allowedDomains.IsAllowed(d) {
+				log.Info("domain is not allowed", slog.String("domain", d))
+				render.JSON(w, r, resp.Error("domain is not allowed"))
+				return
+			}
+			domain = domains.Normalize(d)
+		}
+
+		format, opts, err := parseOptions(query)
+		if err != nil {
+			log.Info("invalid qr options", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		if _, err := urlGetter.GetURL(domain, alias); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to get url", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to get url"))
+			}
+			return
+		}
+
+		content := shortURL(publicBaseURL, domain != allowedDomains.Default(), domain, alias)
+
+		etag := etag(content, format, opts)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var (
+			data        []byte
+			contentType string
+		)
+		switch format {
+		case formatSVG:
+			data, err = libqr.SVG(content, opts)
+			contentType = "image/svg+xml"
+		default:
+			data, err = libqr.PNG(content, opts)
+			contentType = "image/png"
+		}
+		if err != nil {
+			log.Error("failed to render qr code", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to render qr code"))
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data)
+	}
+}
+
+func parseOptions(query url.Values) (string, libqr.Options, error) {
+	opts := libqr.Options{
+		Size:       defaultSize,
+		Margin:     defaultMargin,
+		Level:      qrcode.Medium,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+
+	format := strings.ToLower(query.Get("format"))
+	switch format {
+	case "":
+		format = formatPNG
+	case formatPNG, formatSVG:
+	default:
+		return "", opts, errors.New("format must be png or svg")
+	}
+
+	var err error
+	if v := query.Get("size"); v != "" {
+		opts.Size, err = strconv.Atoi(v)
+		if err != nil || opts.Size < minSize || opts.Size > maxSize {
+			return "", opts, fmt.Errorf("size must be between %d and %d", minSize, maxSize)
+		}
+	}
+	if v := query.Get("margin"); v != "" {
+		opts.Margin, err = strconv.Atoi(v)
+		if err != nil || opts.Margin < 0 || opts.Margin > maxMargin {
+			return "", opts, fmt.Errorf("margin must be between 0 and %d", maxMargin)
+		}
+	}
+	if v := query.Get("level"); v != "" {
+		if opts.Level, err = libqr.ParseLevel(v); err != nil {
+			return "", opts, errors.New("level must be one of L, M, Q, H")
+		}
+	}
+	if v := query.Get("fg"); v != "" {
+		if opts.Foreground, err = libqr.ParseColor(v); err != nil {
+			return "", opts, errors.New("fg must be a hex color")
+		}
+	}
+	if v := query.Get("bg"); v != "" {
+		if opts.Background, err = libqr.ParseColor(v); err != nil {
+			return "", opts, errors.New("bg must be a hex color")
+		}
+	}
+
+	return format, opts, nil
+}
+
+// shortURL builds the public URL of the link. Links on a non-default
+// domain keep the scheme and path prefix of the base URL.
+func shortURL(base *url.URL, otherDomain bool, domain string, alias string) string {
+	u := *base
+	if otherDomain {
+		u.Host = domain
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + alias
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+func etag(content string, format string, opts libqr.Options) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%d|%d|%d|%v|%v",
+		content, format, opts.Size, opts.Margin, opts.Level, opts.Foreground, opts.Background,
+	))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,148 @@
+package qr_test
+
+import (
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	qrcode "github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/qr"
+	"url-shortener/internal/http-server/handlers/url/qr/mocks"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	libqr "url-shortener/internal/lib/qr"
+	"url-shortener/internal/storage"
+)
+
+func TestQRHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		domain      string
+		wantCode    int
+		contentType string
+		content     string
+		opts        libqr.Options
+		svg         bool
+	}{
+		{
+			name:        "Default PNG",
+			domain:      "go.example",
+			wantCode:    http.StatusOK,
+			contentType: "image/png",
+			content:     "https://go.example/s/docs",
+			opts: libqr.Options{
+				Size:       256,
+				Margin:     4,
+				Level:      qrcode.Medium,
+				Foreground: color.RGBA{A: 0xff},
+				Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+			},
+		},
+		{
+			name:        "SVG on other domain",
+			query:       "format=svg&domain=s.example&size=512&margin=1&level=H&fg=%23ff0000&bg=eee",
+			domain:      "s.example",
+			wantCode:    http.StatusOK,
+			contentType: "image/svg+xml",
+			content:     "https://s.example/s/docs",
+			opts: libqr.Options{
+				Size:       512,
+				Margin:     1,
+				Level:      qrcode.Highest,
+				Foreground: color.RGBA{R: 0xff, A: 0xff},
+				Background: color.RGBA{R: 0xee, G: 0xee, B: 0xee, A: 0xff},
+			},
+			svg: true,
+		},
+		{
+			name:     "Invalid size",
+			query:    "size=100000",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid format",
+			query:    "format=gif",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid color",
+			query:    "fg=blue",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			if tc.domain != "" {
+				urlGetterMock.On("GetURL", tc.domain, "docs").
+					Return(storage.URL{Alias: "docs", URL: "https://example.com"}, nil)
+			}
+
+			r := newRouter(urlGetterMock)
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs/qr?"+tc.query, nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+
+			var want []byte
+			var err error
+			if tc.svg {
+				want, err = libqr.SVG(tc.content, tc.opts)
+			} else {
+				want, err = libqr.PNG(tc.content, tc.opts)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, want, rr.Body.Bytes())
+
+			etag := rr.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+
+			req := httptest.NewRequest(http.MethodGet, "/docs/qr?"+tc.query, nil)
+			req.Header.Set("If-None-Match", etag)
+
+			rr = httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusNotModified, rr.Code)
+			assert.Empty(t, rr.Body.Bytes())
+		})
+	}
+}
+
+func TestQRHandler_NotFound(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", "go.example", "missing").
+		Return(storage.URL{}, storage.ErrURLNotFound).Once()
+
+	rr := httptest.NewRecorder()
+	newRouter(urlGetterMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing/qr", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func newRouter(urlGetter qr.URLGetter) http.Handler {
+	base, _ := url.Parse("https://go.example/s/")
+
+	r := chi.NewRouter()
+	r.Get("/{alias}/qr", qr.New(
+		slogdiscard.NewDiscardLogger(),
+		urlGetter,
+		domains.New("go.example", []string{"s.example"}),
+		base,
+	))
+	return r
+}
@@ -0,0 +1,153 @@
+package redirect
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/throttle"
+	"url-shortener/internal/storage"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var passwordPage = template.Must(template.ParseFS(templates, "templates/password.html"))
+
+// maxPasswordFormSize limits the body of a password form submission.
+const maxPasswordFormSize = 4 << 10
+
+// PasswordGate guards password protected links. A correct password is
+// remembered in a short-lived cookie signed with secret, so repeat visits
+// skip the form. Wrong passwords are throttled per link and client IP.
+type PasswordGate struct {
+	secret   []byte
+	ttl      time.Duration
+	throttle *throttle.Limiter
+	now      func() time.Time
+}
+
+func NewPasswordGate(secret []byte, ttl time.Duration, throttle *throttle.Limiter) *PasswordGate {
+	return &PasswordGate{
+		secret:   secret,
+		ttl:      ttl,
+		throttle: throttle,
+		now:      time.Now,
+	}
+}
+
+type passwordPageData struct {
+	Alias string
+	Error string
+}
+
+// hasAccess reports whether the request carries a valid cookie for u.
+func (g *PasswordGate) hasAccess(r *http.Request, u storage.URL) bool {
+	c, err := r.Cookie(cookieName(u))
+	if err != nil {
+		return false
+	}
+
+	rawExpires, sig, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(rawExpires, 10, 64)
+	if err != nil || g.now().Unix() >= expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(g.sign(u, expires)))
+}
+
+// serve shows the password form and checks submitted passwords.
+func (g *PasswordGate) serve(w http.ResponseWriter, r *http.Request, log *slog.Logger, u storage.URL) {
+	if r.Method != http.MethodPost {
+		g.render(w, log, http.StatusOK, u, "")
+		return
+	}
+
+	key := u.Domain + "/" + u.Alias + "|" + clientIP(r)
+
+	// Allow counts the attempt up front; only a correct password clears it.
+	if wait, ok := g.throttle.Allow(key); !ok {
+		log.Info("password attempts throttled", slog.String("alias", u.Alias), slog.String("key", key))
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		g.render(w, log, http.StatusTooManyRequests, u, "Too many attempts. Try again later.")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+	password := r.PostFormValue("password")
+
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		log.Info("wrong link password", slog.String("alias", u.Alias))
+		g.render(w, log, http.StatusUnauthorized, u, "Wrong password.")
+		return
+	}
+
+	g.throttle.Reset(key)
+
+	expires := g.now().Add(g.ttl)
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName(u),
+		Value:    strconv.FormatInt(expires.Unix(), 10) + "." + g.sign(u, expires.Unix()),
+		Path:     "/" + url.PathEscape(u.Alias),
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// Come back with GET so the regular redirect (and click) happens.
+	http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+}
+
+func (g *PasswordGate) render(w http.ResponseWriter, log *slog.Logger, code int, u storage.URL, msg string) {
+	var buf bytes.Buffer
+	if err := passwordPage.Execute(&buf, passwordPageData{Alias: u.Alias, Error: msg}); err != nil {
+		log.Error("failed to render password form", sl.Err(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// sign binds the cookie to the link and its current password hash,
+// so changing the password invalidates issued cookies.
+func (g *PasswordGate) sign(u storage.URL, expires int64) string {
+	mac := hmac.New(sha256.New, g.secret)
+	_, _ = fmt.Fprintf(mac, "%d|%d|%s", u.ID, expires, u.PasswordHash)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cookieName(u storage.URL) string {
+	return "link_pass_" + strconv.FormatInt(u.ID, 10)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
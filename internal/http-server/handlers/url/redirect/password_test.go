@@ -0,0 +1,160 @@
+package redirect_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"url-shortener/internal/http-server/handlers/url/redirect"
+	"url-shortener/internal/http-server/handlers/url/redirect/mocks"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestGetURLHandler_Password(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	link := storage.URL{
+		ID:           3,
+		Domain:       "go.example",
+		Alias:        "docs",
+		URL:          "https://example.com/docs",
+		PasswordHash: string(hash),
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", "go.example", "docs").Return(link, nil)
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+
+	handler := redirect.New(
+		slogdiscard.NewDiscardLogger(),
+		urlGetterMock,
+		clickRecorderMock,
+		domains.New("go.example", nil),
+		newPasswordGate(),
+		time.Now,
+	)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Post("/{alias}", handler)
+
+	post := func(password string, remoteAddr string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		req := httptest.NewRequest(http.MethodPost, "/docs", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// No cookie: the form is shown instead of redirecting.
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<form method="post">`)
+	assert.NotContains(t, rr.Body.String(), link.URL)
+
+	// Wrong passwords are throttled per client.
+	for i := 0; i < 3; i++ {
+		rr = post("wrong", "10.0.0.1:1234")
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+	rr = post("secret", "10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	// Another client is not affected.
+	rr = post("secret", "10.0.0.2:1234")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/docs", rr.Header().Get("Location"))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// The cookie skips the form.
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, link.URL, rr.Header().Get("Location"))
+
+	// A tampered cookie does not.
+	tampered := *cookies[0]
+	tampered.Value += "x"
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	req.AddCookie(&tampered)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestGetURLHandler_PasswordPermanent(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	link := storage.URL{
+		ID:           4,
+		Domain:       "go.example",
+		Alias:        "docs",
+		URL:          "https://example.com/docs",
+		PasswordHash: string(hash),
+		RedirectType: http.StatusMovedPermanently,
+	}
+
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", "go.example", "docs").Return(link, nil)
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).Return(nil).Once()
+
+	handler := redirect.New(
+		slogdiscard.NewDiscardLogger(),
+		urlGetterMock,
+		clickRecorderMock,
+		domains.New("go.example", nil),
+		newPasswordGate(),
+		time.Now,
+	)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+	r.Post("/{alias}", handler)
+
+	form := url.Values{"password": {"secret"}}
+	req := httptest.NewRequest(http.MethodPost, "/docs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	// The redirect is permanent, but must not be cached for other clients.
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	assert.Equal(t, link.URL, rr.Header().Get("Location"))
+	assert.Equal(t, "private, no-store", rr.Header().Get("Cache-Control"))
+}
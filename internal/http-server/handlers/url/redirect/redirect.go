@@ -2,24 +2,47 @@ package redirect
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/routing"
+	"url-shortener/internal/lib/schedule"
 	"url-shortener/internal/storage"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@latest  --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetURL(domain string, alias string) (storage.URL, error)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+//go:generate go run github.com/vektra/mockery/v2@latest  --name=ClickRecorder
+type ClickRecorder interface {
+	RecordClick(click storage.Click) error
+}
+
+// permanentMaxAge bounds how long clients cache permanent redirects,
+// so that a changed target eventually reaches everyone.
+const permanentMaxAge = 24 * time.Hour
+
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	clickRecorder ClickRecorder,
+	allowedDomains *domains.Set,
+	passwords *PasswordGate,
+	clock func() time.Time,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 		log = log.With(
@@ -34,11 +57,13 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		url, err := urlGetter.GetURL(alias)
+		domain := allowedDomains.Resolve(r.Host)
+
+		u, err := urlGetter.GetURL(domain, alias)
 		if err != nil {
 			switch {
 			case errors.Is(err, storage.ErrURLNotFound):
-				log.Info("url not found", slog.String("alias", alias))
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
 				render.JSON(w, r, resp.Error("url not found"))
 			default:
 				log.Error("failed to get url", sl.Err(err))
@@ -47,7 +72,102 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 			return
 		}
 
-		log.Info("url found", slog.String("url", url))
-		http.Redirect(w, r, url, http.StatusFound)
+		now := clock()
+		if now.Before(u.NotBefore) {
+			log.Info("url is not active yet", slog.String("alias", alias), slog.Time("not_before", u.NotBefore))
+			render.JSON(w, r, resp.Error("url not found"))
+			return
+		}
+		if target, ok := schedule.Target(u.Schedule, now); ok {
+			u.URL = target
+		}
+
+		if u.PasswordHash != "" && !passwords.hasAccess(r, u) {
+			passwords.serve(w, r, log, u)
+			return
+		}
+
+		matched := false
+		if len(u.Rules) > 0 {
+			// The target depends on these headers, also for cached 301s.
+			w.Header().Set("Vary", strings.Join(varyHeaders(u.Rules), ", "))
+			if rule, ok := routing.Match(u.Rules, r); ok {
+				log.Debug("routing rule matched", slog.String("alias", alias), slog.String("target", rule.Target))
+				u.URL = rule.Target
+				matched = true
+			}
+		}
+
+		var variant string
+		if !matched && len(u.Split.Variants) > 0 {
+			if v, ok := pickVariant(w, r, u); ok {
+				u.URL = v.Target
+				variant = v.Name
+			}
+		}
+
+		suffix := pathSuffix(r)
+		if suffix != "" && !u.ForwardPath {
+			log.Info("path forwarding is disabled", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("url not found"))
+			return
+		}
+
+		target, err := buildTarget(u, suffix, r.URL.RawQuery)
+		if err != nil {
+			log.Info("failed to build target url", sl.Err(err), slog.String("alias", alias))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid request"))
+			return
+		}
+
+		code := u.RedirectType
+		if code == 0 {
+			code = http.StatusFound
+		}
+		switch {
+		case u.PasswordHash != "":
+			// The redirect depends on the cookie; shared caches must not
+			// hand it to clients that never entered the password.
+			w.Header().Set("Cache-Control", "private, no-store")
+		case variant != "":
+			// A cached redirect would pin every client to one variant.
+			w.Header().Set("Cache-Control", "no-store")
+		case code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect:
+			maxAge := permanentMaxAge
+			// Clients must not keep the old target past a scheduled switch.
+			if next, ok := schedule.Next(u.Schedule, now); ok && next.Sub(now) < maxAge {
+				maxAge = next.Sub(now)
+			}
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		}
+
+		// A lost click must not break the redirect itself.
+		if err := clickRecorder.RecordClick(storage.Click{URLID: u.ID, Variant: variant}); err != nil {
+			log.Error("failed to record click", sl.Err(err), slog.String("alias", alias))
+		}
+
+		log.Info("url found", slog.String("url", target), slog.Int("code", code), slog.String("variant", variant))
+		http.Redirect(w, r, target, code)
 	}
 }
+
+// varyHeaders lists the request headers the rules look at.
+func varyHeaders(rules []storage.Rule) []string {
+	vary := []string{"User-Agent", "Accept-Language"}
+	seen := map[string]bool{"User-Agent": true, "Accept-Language": true}
+
+	for _, rule := range rules {
+		for name := range rule.Headers {
+			name = http.CanonicalHeaderKey(name)
+			if !seen[name] {
+				seen[name] = true
+				vary = append(vary, name)
+			}
+		}
+	}
+
+	// Map iteration order must not change the header between requests.
+	sort.Strings(vary[2:])
+	return vary
+}
@@ -1,17 +1,23 @@
 package redirect_test
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/url/redirect"
 	"url-shortener/internal/http-server/handlers/url/redirect/mocks"
 	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/domains"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/throttle"
+	"url-shortener/internal/storage"
 )
 
 func TestGetURLHandler(t *testing.T) {
@@ -34,12 +40,23 @@ func TestGetURLHandler(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
-					Return(tc.url, tc.mockError).Once()
+				urlGetterMock.On("GetURL", "go.example", tc.alias).
+					Return(storage.URL{URL: tc.url}, tc.mockError).Once()
 			}
 
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+				Return(nil).Once()
+
 			r := chi.NewRouter()
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				domains.New("go.example", nil),
+				newPasswordGate(),
+				time.Now,
+			))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -52,3 +69,372 @@ func TestGetURLHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestGetURLHandler_HostScoped(t *testing.T) {
+	cases := []struct {
+		name   string
+		host   string
+		domain string
+		url    string
+	}{
+		{
+			name:   "Default domain",
+			host:   "go.example",
+			domain: "go.example",
+			url:    "https://go.example.org/docs",
+		},
+		{
+			name:   "Other allowed domain",
+			host:   "s.example:8082",
+			domain: "s.example",
+			url:    "https://s.example.org/docs",
+		},
+		{
+			name:   "Unknown host uses default domain",
+			host:   "127.0.0.1:8082",
+			domain: "go.example",
+			url:    "https://go.example.org/docs",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", tc.domain, "docs").
+				Return(storage.URL{URL: tc.url}, nil).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+				Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				domains.New("go.example", []string{"s.example"}),
+				newPasswordGate(),
+				time.Now,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+			req.Host = tc.host
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.url, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestGetURLHandler_RedirectType(t *testing.T) {
+	cases := []struct {
+		name         string
+		redirectType int
+		wantCode     int
+		cacheControl string
+	}{
+		{
+			name:     "Default",
+			wantCode: http.StatusFound,
+		},
+		{
+			name:         "Moved permanently",
+			redirectType: http.StatusMovedPermanently,
+			wantCode:     http.StatusMovedPermanently,
+			cacheControl: "public, max-age=86400",
+		},
+		{
+			name:         "Temporary redirect",
+			redirectType: http.StatusTemporaryRedirect,
+			wantCode:     http.StatusTemporaryRedirect,
+		},
+		{
+			name:         "Permanent redirect",
+			redirectType: http.StatusPermanentRedirect,
+			wantCode:     http.StatusPermanentRedirect,
+			cacheControl: "public, max-age=86400",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", "go.example", "docs").
+				Return(storage.URL{URL: "https://example.com/docs", RedirectType: tc.redirectType}, nil).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+				Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				domains.New("go.example", nil),
+				newPasswordGate(),
+				time.Now,
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+			require.Equal(t, tc.wantCode, rr.Code)
+			assert.Equal(t, "https://example.com/docs", rr.Header().Get("Location"))
+			assert.Equal(t, tc.cacheControl, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestGetURLHandler_Rules(t *testing.T) {
+	link := storage.URL{
+		URL: "https://example.com",
+		Rules: []storage.Rule{
+			{Platform: "ios", Target: "https://apps.apple.com/app/id1"},
+			{Platform: "android", Target: "https://play.google.com/store/apps/details?id=app"},
+			{Languages: []string{"de"}, Headers: map[string]string{"X-Beta": "*"}, Target: "https://example.com/de/beta"},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "iOS",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"},
+			want:    "https://apps.apple.com/app/id1",
+		},
+		{
+			name:    "Android",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile"},
+			want:    "https://play.google.com/store/apps/details?id=app",
+		},
+		{
+			name:    "Language and header",
+			headers: map[string]string{"Accept-Language": "de-DE,en;q=0.5", "X-Beta": "1"},
+			want:    "https://example.com/de/beta",
+		},
+		{
+			name:    "Language without header falls back",
+			headers: map[string]string{"Accept-Language": "de-DE"},
+			want:    "https://example.com",
+		},
+		{
+			name:    "Desktop falls back",
+			headers: map[string]string{"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"},
+			want:    "https://example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", "go.example", "app").Return(link, nil).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+				Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				domains.New("go.example", nil),
+				newPasswordGate(),
+				time.Now,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/app", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusFound, rr.Code)
+			assert.Equal(t, tc.want, rr.Header().Get("Location"))
+			assert.Equal(t, "User-Agent, Accept-Language, X-Beta", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestGetURLHandler_Split(t *testing.T) {
+	link := storage.URL{
+		ID:           7,
+		Alias:        "landing",
+		URL:          "https://example.com",
+		RedirectType: http.StatusMovedPermanently,
+		Rules:        []storage.Rule{{Platform: "ios", Target: "https://apps.example.com"}},
+		Split: storage.Split{
+			Sticky: true,
+			Variants: []storage.Variant{
+				{Name: "a", Target: "https://example.com/a", Weight: 1},
+				{Name: "retired", Target: "https://example.com/old", Weight: 0},
+			},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		cookie      string
+		userAgent   string
+		want        string
+		wantVariant string
+		wantCookie  bool
+	}{
+		{
+			name:        "New client is assigned",
+			want:        "https://example.com/a",
+			wantVariant: "a",
+			wantCookie:  true,
+		},
+		{
+			name:        "Sticky client keeps its variant",
+			cookie:      "a",
+			want:        "https://example.com/a",
+			wantVariant: "a",
+		},
+		{
+			name:        "Retired variant is reassigned",
+			cookie:      "retired",
+			want:        "https://example.com/a",
+			wantVariant: "a",
+			wantCookie:  true,
+		},
+		{
+			name:      "Rules win over the split",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)",
+			want:      "https://apps.example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", "go.example", "landing").Return(link, nil).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			clickRecorderMock.On("RecordClick", storage.Click{URLID: 7, Variant: tc.wantVariant}).
+				Return(nil).Once()
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				domains.New("go.example", nil),
+				newPasswordGate(),
+				time.Now,
+			))
+
+			req := httptest.NewRequest(http.MethodGet, "/landing", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "link_variant_7", Value: tc.cookie})
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusMovedPermanently, rr.Code)
+			assert.Equal(t, tc.want, rr.Header().Get("Location"))
+
+			if tc.wantVariant != "" {
+				assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			}
+
+			cookies := rr.Result().Cookies()
+			if !tc.wantCookie {
+				assert.Empty(t, cookies)
+				return
+			}
+			require.Len(t, cookies, 1)
+			assert.Equal(t, "link_variant_7", cookies[0].Name)
+			assert.Equal(t, tc.wantVariant, cookies[0].Value)
+			assert.Equal(t, "/landing", cookies[0].Path)
+		})
+	}
+}
+
+func TestGetURLHandler_Schedule(t *testing.T) {
+	launch := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	link := storage.URL{
+		URL:          "https://example.com/teaser",
+		RedirectType: http.StatusMovedPermanently,
+		NotBefore:    launch.Add(-24 * time.Hour),
+		Schedule: []storage.ScheduleEntry{
+			{At: launch, Target: "https://example.com/product"},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		now          time.Time
+		want         string
+		cacheControl string
+	}{
+		{
+			name: "Before activation",
+			now:  launch.Add(-25 * time.Hour),
+		},
+		{
+			name:         "Active before the switch",
+			now:          launch.Add(-time.Hour),
+			want:         "https://example.com/teaser",
+			cacheControl: "public, max-age=3600",
+		},
+		{
+			name:         "After the switch",
+			now:          launch,
+			want:         "https://example.com/product",
+			cacheControl: "public, max-age=86400",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlGetterMock := mocks.NewURLGetter(t)
+			urlGetterMock.On("GetURL", "go.example", "launch").Return(link, nil).Once()
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			if tc.want != "" {
+				clickRecorderMock.On("RecordClick", mock.AnythingOfType("storage.Click")).
+					Return(nil).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				domains.New("go.example", nil),
+				newPasswordGate(),
+				func() time.Time { return tc.now },
+			))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/launch", nil))
+
+			if tc.want == "" {
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.Contains(t, rr.Body.String(), "url not found")
+				return
+			}
+
+			require.Equal(t, http.StatusMovedPermanently, rr.Code)
+			assert.Equal(t, tc.want, rr.Header().Get("Location"))
+			assert.Equal(t, tc.cacheControl, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func newPasswordGate() *redirect.PasswordGate {
+	return redirect.NewPasswordGate([]byte("test-secret"), time.Minute, throttle.New(3, time.Minute))
+}
@@ -0,0 +1,52 @@
+package redirect
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/lib/split"
+	"url-shortener/internal/storage"
+)
+
+// variantCookieTTL is how long a sticky variant assignment is kept.
+const variantCookieTTL = 30 * 24 * time.Hour
+
+// pickVariant chooses the split variant for the request. For sticky
+// splits a still active variant from the cookie wins, and a new choice
+// is stored in it.
+func pickVariant(w http.ResponseWriter, r *http.Request, u storage.URL) (storage.Variant, bool) {
+	name := variantCookieName(u)
+
+	if u.Split.Sticky {
+		if c, err := r.Cookie(name); err == nil {
+			if v, ok := split.Find(u.Split.Variants, c.Value); ok {
+				return v, true
+			}
+		}
+	}
+
+	v, ok := split.Pick(u.Split.Variants, nil)
+	if !ok {
+		return storage.Variant{}, false
+	}
+
+	if u.Split.Sticky {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    v.Name,
+			Path:     "/" + url.PathEscape(u.Alias),
+			MaxAge:   int(variantCookieTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return v, true
+}
+
+func variantCookieName(u storage.URL) string {
+	return "link_variant_" + strconv.FormatInt(u.ID, 10)
+}
@@ -0,0 +1,88 @@
+package redirect
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"url-shortener/internal/storage"
+)
+
+var errInvalidPath = errors.New("invalid path")
+
+// buildTarget applies the link's passthrough options to its target URL.
+// suffix is the escaped path after "/{alias}/" and query is the raw
+// incoming query string.
+func buildTarget(u storage.URL, suffix string, query string) (string, error) {
+	if (suffix == "" || !u.ForwardPath) && (query == "" || !u.ForwardQuery) {
+		return u.URL, nil
+	}
+
+	target, err := url.Parse(u.URL)
+	if err != nil {
+		return "", err
+	}
+
+	if suffix != "" && u.ForwardPath {
+		if err := appendPath(target, suffix); err != nil {
+			return "", err
+		}
+	}
+
+	if query != "" && u.ForwardQuery {
+		incoming, err := url.ParseQuery(query)
+		if err != nil {
+			return "", err
+		}
+		target.RawQuery = mergeQuery(target.Query(), incoming, u.QueryPrecedence).Encode()
+	}
+
+	return target.String(), nil
+}
+
+// pathSuffix returns the escaped part of the request path after the alias.
+// It is taken from the raw path rather than the chi wildcard, since the
+// URLFormat middleware strips extensions from the routing path.
+func pathSuffix(r *http.Request) string {
+	_, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	return rest
+}
+
+// appendPath appends escaped path segments to the target path. Every
+// segment is re-escaped, and dot segments are rejected so the suffix
+// cannot climb above the target's own path.
+func appendPath(target *url.URL, suffix string) error {
+	segments := strings.Split(suffix, "/")
+	for i, seg := range segments {
+		unescaped, err := url.PathUnescape(seg)
+		if err != nil {
+			return errInvalidPath
+		}
+		if unescaped == "." || unescaped == ".." {
+			return errInvalidPath
+		}
+		segments[i] = url.PathEscape(unescaped)
+	}
+
+	joined := strings.TrimSuffix(target.EscapedPath(), "/") + "/" + strings.Join(segments, "/")
+
+	path, err := url.PathUnescape(joined)
+	if err != nil {
+		return errInvalidPath
+	}
+	target.Path = path
+	target.RawPath = joined
+
+	return nil
+}
+
+func mergeQuery(target, incoming url.Values, precedence string) url.Values {
+	for key, values := range incoming {
+		if _, exists := target[key]; exists && precedence != storage.QueryPrecedenceIncoming {
+			continue
+		}
+		target[key] = values
+	}
+	return target
+}
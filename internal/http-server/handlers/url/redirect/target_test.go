@@ -0,0 +1,104 @@
+package redirect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+)
+
+func TestBuildTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    storage.URL
+		suffix  string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Options disabled",
+			link:   storage.URL{URL: "https://example.com/docs?a=1"},
+			suffix: "extra",
+			query:  "utm_source=x",
+			want:   "https://example.com/docs?a=1",
+		},
+		{
+			name:  "Query appended",
+			link:  storage.URL{URL: "https://example.com/docs", ForwardQuery: true},
+			query: "utm_source=x",
+			want:  "https://example.com/docs?utm_source=x",
+		},
+		{
+			name: "Target wins by default",
+			link: storage.URL{
+				URL:             "https://example.com/docs?a=1&b=2",
+				ForwardQuery:    true,
+				QueryPrecedence: storage.QueryPrecedenceTarget,
+			},
+			query: "a=9&c=3",
+			want:  "https://example.com/docs?a=1&b=2&c=3",
+		},
+		{
+			name: "Incoming wins",
+			link: storage.URL{
+				URL:             "https://example.com/docs?a=1&b=2",
+				ForwardQuery:    true,
+				QueryPrecedence: storage.QueryPrecedenceIncoming,
+			},
+			query: "a=9&a=8",
+			want:  "https://example.com/docs?a=9&a=8&b=2",
+		},
+		{
+			name:   "Path appended",
+			link:   storage.URL{URL: "https://example.com/docs/", ForwardPath: true},
+			suffix: "guide/intro",
+			want:   "https://example.com/docs/guide/intro",
+		},
+		{
+			name:   "Path appended to root",
+			link:   storage.URL{URL: "https://example.com", ForwardPath: true},
+			suffix: "guide",
+			want:   "https://example.com/guide",
+		},
+		{
+			name:   "Path segments are escaped",
+			link:   storage.URL{URL: "https://example.com/docs", ForwardPath: true},
+			suffix: "a%20b/c%2Fd/%3F",
+			want:   "https://example.com/docs/a%20b/c%2Fd/%3F",
+		},
+		{
+			name:   "Path and query",
+			link:   storage.URL{URL: "https://example.com/docs?a=1", ForwardPath: true, ForwardQuery: true},
+			suffix: "guide",
+			query:  "b=2",
+			want:   "https://example.com/docs/guide?a=1&b=2",
+		},
+		{
+			name:    "Dot segments rejected",
+			link:    storage.URL{URL: "https://example.com/docs", ForwardPath: true},
+			suffix:  "../admin",
+			wantErr: true,
+		},
+		{
+			name:    "Escaped dot segments rejected",
+			link:    storage.URL{URL: "https://example.com/docs", ForwardPath: true},
+			suffix:  "%2e%2e/admin",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTarget(tt.link, tt.suffix, tt.query)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package rules
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/routing"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	// Rules replace the current ones; an empty list removes them.
+	Rules []storage.Rule `json:"rules"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=RulesSetter
+type RulesSetter interface {
+	SetRules(domain string, alias string, rules []storage.Rule) error
+}
+
+// New replaces the routing rules of a link.
+func New(
+	log *slog.Logger,
+	rulesSetter RulesSetter,
+	allowedDomains *domains.Set,
+	policy urlpolicy.Policy,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is empty"))
+			return
+		}
+
+		domain := allowedDomains.Default()
+		if d := r.URL.Query().Get("domain"); d != "" {
+			if !allowedDomains.IsAllowed(d) {
+				log.Info("domain is not allowed", slog.String("domain", d))
+				render.JSON(w, r, resp.Error("domain is not allowed"))
+				return
+			}
+			domain = domains.Normalize(d)
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		if err := routing.Validate(req.Rules); err != nil {
+			log.Info("invalid rules", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		if err := routing.CheckTargets(r.Context(), policy, req.Rules); err != nil {
+			log.Info("rule target rejected by policy", sl.Err(err))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		if err := rulesSetter.SetRules(domain, alias, req.Rules); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to set rules", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to set rules"))
+			}
+			return
+		}
+
+		log.Info("rules updated", slog.String("alias", alias), slog.String("domain", domain), slog.Int("rules", len(req.Rules)))
+		render.JSON(w, r, resp.OK())
+	}
+}
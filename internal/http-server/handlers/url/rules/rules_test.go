@@ -0,0 +1,110 @@
+package rules_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/rules"
+	"url-shortener/internal/http-server/handlers/url/rules/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy/urlpolicytest"
+	"url-shortener/internal/storage"
+)
+
+func TestRulesHandler(t *testing.T) {
+	iosRule := []storage.Rule{{Platform: "ios", Target: "https://apps.example.com/app"}}
+
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		domain    string
+		rules     []storage.Rule
+		setErr    error
+		wantError string
+	}{
+		{
+			name:   "Replace",
+			body:   `{"rules": [{"platform": "ios", "target": "https://apps.example.com/app"}]}`,
+			domain: "go.example",
+			rules:  iosRule,
+		},
+		{
+			name:   "Clear on other domain",
+			query:  "domain=s.example",
+			body:   `{"rules": []}`,
+			domain: "s.example",
+			rules:  []storage.Rule{},
+		},
+		{
+			name:      "Invalid rule",
+			body:      `{"rules": [{"target": "https://apps.example.com/app"}]}`,
+			wantError: "rule 1: invalid rule: at least one condition is required",
+		},
+		{
+			name:      "Target rejected by policy",
+			body:      `{"rules": [{"platform": "ios", "target": "ftp://files.example.com/app"}]}`,
+			wantError: `url is not allowed: rule 1: scheme is not allowed: "ftp"`,
+		},
+		{
+			name:      "Invalid body",
+			body:      `{`,
+			wantError: "invalid request body",
+		},
+		{
+			name:      "Not found",
+			body:      `{"rules": [{"platform": "ios", "target": "https://apps.example.com/app"}]}`,
+			domain:    "go.example",
+			rules:     iosRule,
+			setErr:    storage.ErrURLNotFound,
+			wantError: "url not found",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"rules": [{"platform": "ios", "target": "https://apps.example.com/app"}]}`,
+			domain:    "go.example",
+			rules:     iosRule,
+			setErr:    errors.New("unexpected error"),
+			wantError: "failed to set rules",
+		},
+	}
+
+	policy := urlpolicytest.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setter := mocks.NewRulesSetter(t)
+			if tc.domain != "" {
+				setter.On("SetRules", tc.domain, "app", tc.rules).Return(tc.setErr).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Put("/url/{alias}/rules", rules.New(
+				slogdiscard.NewDiscardLogger(),
+				setter,
+				domains.New("go.example", []string{"s.example"}),
+				policy,
+			))
+
+			req := httptest.NewRequest(http.MethodPut, "/url/app/rules?"+tc.query, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+}
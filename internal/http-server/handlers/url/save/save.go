@@ -1,39 +1,131 @@
 package save
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+	"url-shortener/internal/http-server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/routing"
+	"url-shortener/internal/lib/schedule"
+	"url-shortener/internal/lib/split"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/lib/urlpolicy"
 	"url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type Request struct {
-	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	URL    string `json:"url" validate:"required,url"`
+	Alias  string `json:"alias,omitempty"`
+	Domain string `json:"domain,omitempty"`
+	// RedirectType is the HTTP status used when following the link.
+	// Defaults to 302.
+	RedirectType int `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// ForwardQuery passes the incoming query string on to the target.
+	ForwardQuery bool `json:"forward_query,omitempty"`
+	// QueryPrecedence is "target" (default) or "incoming" and decides
+	// whose value wins when both queries have the same key.
+	QueryPrecedence string `json:"query_precedence,omitempty" validate:"omitempty,oneof=target incoming"`
+	// ForwardPath makes /{alias}/rest append "rest" to the target path.
+	ForwardPath bool   `json:"forward_path,omitempty"`
+	Title       string `json:"title,omitempty" validate:"max=256"`
+	Notes       string `json:"notes,omitempty" validate:"max=4096"`
+	// Password protects the link with a form shown before redirecting.
+	// bcrypt only uses the first 72 bytes, so longer ones are rejected,
+	// see maxPasswordBytes.
+	Password string `json:"password,omitempty" validate:"omitempty,min=4"`
+	// Rules send matching requests to other targets, see package routing.
+	Rules []storage.Rule `json:"rules,omitempty"`
+	// Split spreads requests over weighted variants, see package split.
+	Split storage.Split `json:"split"`
+	// NotBefore keeps the link inactive until the given time.
+	NotBefore time.Time `json:"not_before,omitempty"`
+	// Schedule switches the target at the given times, see package schedule.
+	Schedule []storage.ScheduleEntry `json:"schedule,omitempty"`
+	// Tags are normalized to lowercase, see package tags.
+	Tags []string `json:"tags,omitempty"`
+	// Metadata is a free-form JSON object stored with the link.
+	Metadata map[string]json.RawMessage `json:"metadata,omitempty"`
+}
+
+// LogValue keeps the password out of the logs.
+func (r Request) LogValue() slog.Value {
+	type request Request
+	if r.Password != "" {
+		r.Password = "[redacted]"
+	}
+	return slog.AnyValue(request(r))
 }
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Domain string `json:"domain,omitempty"`
 }
 
 const (
 	aliasLength = 6
+	// maxPasswordBytes is the bcrypt limit; it counts bytes, so it is
+	// checked apart from the validator, which counts characters.
+	maxPasswordBytes = 72
 )
 
+// reservedAliases are paths of fixed routes that would shadow the
+// redirect or the details of links with these aliases.
+var reservedAliases = map[string]bool{
+	"broken":  true,
+	"tags":    true,
+	"export":  true,
+	"search":  true,
+	"keys":    true,
+	"url":     true,
+	"healthz": true,
+	"tokens":  true,
+}
+
+// checkAlias rejects aliases that could not be reached: reserved ones,
+// and those with "/", which starts the path forwarded to the target, or
+// "+", which asks for the preview page.
+func checkAlias(alias string) error {
+	if reservedAliases[alias] {
+		return fmt.Errorf("alias %q is reserved", alias)
+	}
+	if strings.ContainsAny(alias, "/+") {
+		return fmt.Errorf("alias must not contain %q or %q", "/", "+")
+	}
+	return nil
+}
+
 //go:generate go run github.com/vektra/mockery/v2@latest --name=URLSaver
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	SaveURL(u storage.URL) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=MetadataFetcher
+type MetadataFetcher interface {
+	Enqueue(urlID int64, target string) error
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// New saves a link. fetcher may be nil to skip fetching page metadata.
+func New(
+	log *slog.Logger,
+	urlSaver URLSaver,
+	allowedDomains *domains.Set,
+	policy urlpolicy.Policy,
+	fetcher MetadataFetcher,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
@@ -56,15 +148,131 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			return
 		}
 
+		if len(req.Password) > maxPasswordBytes {
+			log.Info("password is too long", slog.Int("bytes", len(req.Password)))
+			render.JSON(w, r, resp.Error(fmt.Sprintf("поле Password должно быть не длиннее %d байт", maxPasswordBytes)))
+			return
+		}
+
+		if err := checkAlias(req.Alias); err != nil {
+			log.Info("invalid alias", sl.Err(err), slog.String("alias", req.Alias))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		if err := policy.Check(r.Context(), req.URL); err != nil {
+			log.Info("url rejected by policy", sl.Err(err), slog.String("url", req.URL))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		if err := routing.Validate(req.Rules); err != nil {
+			log.Info("invalid rules", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err := routing.CheckTargets(r.Context(), policy, req.Rules); err != nil {
+			log.Info("rule target rejected by policy", sl.Err(err))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		if err := split.Validate(req.Split); err != nil {
+			log.Info("invalid split", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err := split.CheckTargets(r.Context(), policy, req.Split); err != nil {
+			log.Info("variant target rejected by policy", sl.Err(err))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		entries, err := schedule.Normalize(req.Schedule)
+		if err != nil {
+			log.Info("invalid schedule", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err := schedule.CheckTargets(r.Context(), policy, entries); err != nil {
+			log.Info("scheduled target rejected by policy", sl.Err(err))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		linkTags, err := tags.Normalize(req.Tags)
+		if err != nil {
+			log.Info("invalid tags", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if err := tags.ValidateMetadata(req.Metadata); err != nil {
+			log.Info("invalid metadata", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		domain := allowedDomains.Default()
+		if req.Domain != "" {
+			if !allowedDomains.IsAllowed(req.Domain) {
+				log.Info("domain is not allowed", slog.String("domain", req.Domain))
+				render.JSON(w, r, resp.Error("поле Domain должно быть одним из разрешённых доменов"))
+				return
+			}
+			domain = domains.Normalize(req.Domain)
+		}
+
 		alias := req.Alias
-		if alias == "" {
+		for alias == "" || reservedAliases[alias] {
 			alias = random.NewRandomString(aliasLength)
 		}
 
-		if _, err := urlSaver.SaveURL(req.URL, alias); err != nil {
+		redirectType := req.RedirectType
+		if redirectType == 0 {
+			redirectType = http.StatusFound
+		}
+
+		var passwordHash string
+		if req.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				log.Error("failed to hash password", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to save url"))
+				return
+			}
+			passwordHash = string(hash)
+		}
+
+		queryPrecedence := req.QueryPrecedence
+		if queryPrecedence == "" {
+			queryPrecedence = storage.QueryPrecedenceTarget
+		}
+
+		ownerID, _ := auth.UserIDFromContext(r.Context())
+
+		id, err := urlSaver.SaveURL(storage.URL{
+			Domain:          domain,
+			Alias:           alias,
+			URL:             req.URL,
+			RedirectType:    redirectType,
+			ForwardQuery:    req.ForwardQuery,
+			QueryPrecedence: queryPrecedence,
+			ForwardPath:     req.ForwardPath,
+			Title:           req.Title,
+			Notes:           req.Notes,
+			OwnerID:         ownerID,
+			PasswordHash:    passwordHash,
+			Rules:           req.Rules,
+			Split:           req.Split,
+			NotBefore:       req.NotBefore,
+			Schedule:        entries,
+			Tags:            linkTags,
+			Metadata:        req.Metadata,
+		})
+		if err != nil {
 			switch {
 			case errors.Is(err, storage.ErrURLAlreadyExists):
-				log.Info("url already exists", slog.String("alias", alias))
+				log.Info("url already exists", slog.String("alias", alias), slog.String("domain", domain))
 				render.JSON(w, r, resp.Error("url already exists"))
 			default:
 				log.Error("failed to save url", sl.Err(err))
@@ -73,9 +281,17 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			return
 		}
 
+		if fetcher != nil {
+			if err := fetcher.Enqueue(id, req.URL); err != nil {
+				// The link is saved; it only lacks metadata.
+				log.Warn("failed to enqueue metadata fetch", sl.Err(err))
+			}
+		}
+
 		render.JSON(w, r, Response{
 			Response: resp.OK(),
 			Alias:    alias,
+			Domain:   domain,
 		})
 	}
 }
@@ -7,23 +7,37 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/domains"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy/urlpolicytest"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name             string
+		alias            string
+		url              string
+		domain           string
+		wantDomain       string
+		redirectType     int
+		wantRedirectType int
+		password         string
+		rules            string
+		tags             string
+		wantTags         []string
+		respError        string
+		mockError        error
 	}{
 		{
 			name:  "Success",
@@ -35,6 +49,66 @@ func TestSaveHandler(t *testing.T) {
 			alias: "",
 			url:   "https://google.com",
 		},
+		{
+			name:      "Alias with slash",
+			alias:     "docs/v2",
+			url:       "https://google.com",
+			respError: `alias must not contain "/" or "+"`,
+		},
+		{
+			name:      "Alias with plus",
+			alias:     "docs+",
+			url:       "https://google.com",
+			respError: `alias must not contain "/" or "+"`,
+		},
+		{
+			name:      "Alias shadowed by /url/broken",
+			alias:     "broken",
+			url:       "https://google.com",
+			respError: `alias "broken" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /url/tags",
+			alias:     "tags",
+			url:       "https://google.com",
+			respError: `alias "tags" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /url/export",
+			alias:     "export",
+			url:       "https://google.com",
+			respError: `alias "export" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /url/search",
+			alias:     "search",
+			url:       "https://google.com",
+			respError: `alias "search" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /url/keys",
+			alias:     "keys",
+			url:       "https://google.com",
+			respError: `alias "keys" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /url",
+			alias:     "url",
+			url:       "https://google.com",
+			respError: `alias "url" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /healthz",
+			alias:     "healthz",
+			url:       "https://google.com",
+			respError: `alias "healthz" is reserved`,
+		},
+		{
+			name:      "Alias shadowed by /url/tokens",
+			alias:     "tokens",
+			url:       "https://google.com",
+			respError: `alias "tokens" is reserved`,
+		},
 		{
 			name:      "Empty URL",
 			url:       "",
@@ -47,6 +121,106 @@ func TestSaveHandler(t *testing.T) {
 			alias:     "some_alias",
 			respError: "поле URL должно быть валидным URL",
 		},
+		{
+			name:       "Allowed domain",
+			alias:      "test_alias",
+			url:        "https://google.com",
+			domain:     "S.Example",
+			wantDomain: "s.example",
+		},
+		{
+			name:      "Not allowed domain",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			domain:    "evil.example",
+			respError: "поле Domain должно быть одним из разрешённых доменов",
+		},
+		{
+			name:             "Permanent redirect",
+			alias:            "test_alias",
+			url:              "https://google.com",
+			redirectType:     http.StatusPermanentRedirect,
+			wantRedirectType: http.StatusPermanentRedirect,
+		},
+		{
+			name:         "Invalid redirect type",
+			alias:        "test_alias",
+			url:          "https://google.com",
+			redirectType: http.StatusOK,
+			respError:    "поле RedirectType должно быть одним из: 301 302 307 308",
+		},
+		{
+			name:     "With password",
+			alias:    "test_alias",
+			url:      "https://google.com",
+			password: "secret",
+		},
+		{
+			name:      "Short password",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			password:  "abc",
+			respError: "поле Password должно быть не короче 4",
+		},
+		{
+			name:     "Multibyte password at the limit",
+			alias:    "test_alias",
+			url:      "https://google.com",
+			password: strings.Repeat("пароль", 6),
+		},
+		{
+			name:      "Multibyte password over the limit",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			password:  strings.Repeat("пароль", 6) + "!",
+			respError: "поле Password должно быть не длиннее 72 байт",
+		},
+		{
+			name:      "Scheme not allowed",
+			url:       "javascript:alert(1)",
+			alias:     "some_alias",
+			respError: `url is not allowed: scheme is not allowed: "javascript"`,
+		},
+		{
+			name:      "Private address",
+			url:       "http://169.254.169.254/latest/meta-data",
+			alias:     "some_alias",
+			respError: "url is not allowed: url points to a private network: 169.254.169.254",
+		},
+		{
+			name:  "With rules",
+			alias: "app",
+			url:   "https://example.com",
+			rules: `[{"platform": "ios", "target": "https://apps.example.com/app"}]`,
+		},
+		{
+			name:      "Invalid rule",
+			alias:     "app",
+			url:       "https://example.com",
+			rules:     `[{"platform": "symbian", "target": "https://apps.example.com/app"}]`,
+			respError: `rule 1: invalid rule: unknown platform "symbian"`,
+		},
+		{
+			name:      "Rule target rejected by policy",
+			alias:     "app",
+			url:       "https://example.com",
+			rules:     `[{"platform": "ios", "target": "http://127.0.0.1/app"}]`,
+			respError: "url is not allowed: rule 1: url points to a private network: 127.0.0.1",
+		},
+		{
+			name:     "With tags",
+			alias:    "promo",
+			url:      "https://example.com",
+			tags:     `[" Spring-Sale ", "team:growth", "spring-sale"]`,
+			wantTags: []string{"spring-sale", "team:growth"},
+		},
+		{
+			name:      "Invalid tag",
+			alias:     "promo",
+			url:       "https://example.com",
+			tags:      `["no spaces"]`,
+			respError: `invalid tag: "no spaces"`,
+		},
 		{
 			name:      "SaveURL Error",
 			alias:     "test_alias",
@@ -63,16 +237,57 @@ func TestSaveHandler(t *testing.T) {
 			t.Parallel()
 
 			urlSaverMock := mocks.NewURLSaver(t)
+			fetcherMock := mocks.NewMetadataFetcher(t)
+
+			wantDomain := tc.wantDomain
+			if wantDomain == "" {
+				wantDomain = "go.example"
+			}
+
+			wantRedirectType := tc.wantRedirectType
+			if wantRedirectType == 0 {
+				wantRedirectType = http.StatusFound
+			}
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveURL", mock.MatchedBy(func(u storage.URL) bool {
+					return u.URL == tc.url &&
+						u.Alias != "" &&
+						u.Domain == wantDomain &&
+						u.RedirectType == wantRedirectType &&
+						(tc.rules == "") == (len(u.Rules) == 0) &&
+						assert.ObjectsAreEqual(tc.wantTags, u.Tags) &&
+						checkPassword(u.PasswordHash, tc.password)
+				})).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
+			if tc.respError == "" {
+				fetcherMock.On("Enqueue", int64(1), tc.url).Return(nil).Once()
+			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			handler := save.New(
+				slogdiscard.NewDiscardLogger(),
+				urlSaverMock,
+				domains.New("go.example", []string{"s.example"}),
+				urlpolicytest.New(t),
+				fetcherMock,
+			)
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			rules := tc.rules
+			if rules == "" {
+				rules = "null"
+			}
+
+			tags := tc.tags
+			if tags == "" {
+				tags = "null"
+			}
+
+			input := fmt.Sprintf(
+				`{"url": "%s", "alias": "%s", "domain": "%s", "redirect_type": %d, "password": "%s", "rules": %s, "tags": %s}`,
+				tc.url, tc.alias, tc.domain, tc.redirectType, tc.password, rules, tags,
+			)
 
 			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
@@ -93,4 +308,11 @@ func TestSaveHandler(t *testing.T) {
 			// TODO: add more checks
 		})
 	}
-}
\ No newline at end of file
+}
+
+func checkPassword(hash string, password string) bool {
+	if password == "" {
+		return hash == ""
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
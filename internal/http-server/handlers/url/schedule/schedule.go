@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	libschedule "url-shortener/internal/lib/schedule"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+// Request replaces both the activation time and the schedule.
+type Request struct {
+	// NotBefore keeps the link inactive until the given time; omit it to
+	// activate the link immediately.
+	NotBefore time.Time               `json:"not_before,omitempty"`
+	Entries   []storage.ScheduleEntry `json:"entries"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=ScheduleSetter
+type ScheduleSetter interface {
+	SetSchedule(domain string, alias string, notBefore time.Time, entries []storage.ScheduleEntry) error
+}
+
+// New replaces the activation time and target schedule of a link.
+func New(
+	log *slog.Logger,
+	scheduleSetter ScheduleSetter,
+	allowedDomains *domains.Set,
+	policy urlpolicy.Policy,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.schedule.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is empty"))
+			return
+		}
+
+		domain := allowedDomains.Default()
+		if d := r.URL.Query().Get("domain"); d != "" {
+			if !allowedDomains.IsAllowed(d) {
+				log.Info("domain is not allowed", slog.String("domain", d))
+				render.JSON(w, r, resp.Error("domain is not allowed"))
+				return
+			}
+			domain = domains.Normalize(d)
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		entries, err := libschedule.Normalize(req.Entries)
+		if err != nil {
+			log.Info("invalid schedule", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		if err := libschedule.CheckTargets(r.Context(), policy, entries); err != nil {
+			log.Info("scheduled target rejected by policy", sl.Err(err))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		if err := scheduleSetter.SetSchedule(domain, alias, req.NotBefore, entries); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to set schedule", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to set schedule"))
+			}
+			return
+		}
+
+		log.Info("schedule updated", slog.String("alias", alias), slog.String("domain", domain), slog.Int("entries", len(entries)))
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,119 @@
+package schedule_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/schedule"
+	"url-shortener/internal/http-server/handlers/url/schedule/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy/urlpolicytest"
+	"url-shortener/internal/storage"
+)
+
+func TestScheduleHandler(t *testing.T) {
+	const body = `{"not_before": "2026-02-28T09:00:00Z", "entries": [
+		{"at": "2026-04-01T12:00:00+03:00", "target": "https://example.com/archive"},
+		{"at": "2026-03-01T09:00:00Z", "target": "https://example.com/product"}
+	]}`
+
+	notBefore := time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC)
+	entries := []storage.ScheduleEntry{
+		{At: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), Target: "https://example.com/product"},
+		{At: time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC), Target: "https://example.com/archive"},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		domain    string
+		notBefore time.Time
+		entries   []storage.ScheduleEntry
+		setErr    error
+		wantError string
+	}{
+		{
+			name:      "Replace",
+			body:      body,
+			domain:    "go.example",
+			notBefore: notBefore,
+			entries:   entries,
+		},
+		{
+			name:    "Clear on other domain",
+			query:   "domain=s.example",
+			body:    `{"entries": []}`,
+			domain:  "s.example",
+			entries: []storage.ScheduleEntry{},
+		},
+		{
+			name:      "Invalid entry",
+			body:      `{"entries": [{"at": "2026-03-01T09:00:00Z", "target": "/product"}]}`,
+			wantError: "invalid schedule: target of the entry at 2026-03-01T09:00:00Z must be an absolute url",
+		},
+		{
+			name:      "Target rejected by policy",
+			body:      `{"entries": [{"at": "2026-03-01T09:00:00Z", "target": "http://127.0.0.1/product"}]}`,
+			wantError: "url is not allowed: entry at 2026-03-01T09:00:00Z: url points to a private network: 127.0.0.1",
+		},
+		{
+			name:      "Not found",
+			body:      body,
+			domain:    "go.example",
+			notBefore: notBefore,
+			entries:   entries,
+			setErr:    storage.ErrURLNotFound,
+			wantError: "url not found",
+		},
+		{
+			name:      "Storage error",
+			body:      body,
+			domain:    "go.example",
+			notBefore: notBefore,
+			entries:   entries,
+			setErr:    errors.New("unexpected error"),
+			wantError: "failed to set schedule",
+		},
+	}
+
+	policy := urlpolicytest.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setter := mocks.NewScheduleSetter(t)
+			if tc.domain != "" {
+				setter.On("SetSchedule", tc.domain, "launch", tc.notBefore, tc.entries).Return(tc.setErr).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Put("/url/{alias}/schedule", schedule.New(
+				slogdiscard.NewDiscardLogger(),
+				setter,
+				domains.New("go.example", []string{"s.example"}),
+				policy,
+			))
+
+			req := httptest.NewRequest(http.MethodPut, "/url/launch/schedule?"+tc.query, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+}
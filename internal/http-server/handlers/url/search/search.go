@@ -0,0 +1,139 @@
+package search
+
+import (
+	"html"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/handlers/url/details"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	maxQueryLen  = 256
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLSearcher
+type URLSearcher interface {
+	SearchURLs(query string, limit int, offset int) ([]storage.SearchResult, int, error)
+}
+
+// Highlights are the searched fields as HTML with matches in <mark>.
+type Highlights struct {
+	Alias string `json:"alias"`
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Notes string `json:"notes,omitempty"`
+	Tags  string `json:"tags,omitempty"`
+}
+
+type Result struct {
+	Link       *details.Link `json:"link"`
+	Highlights Highlights    `json:"highlights"`
+	Rank       float64       `json:"rank"`
+}
+
+type Response struct {
+	resp.Response
+	Results []Result `json:"results"`
+	Total   int      `json:"total"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+}
+
+var highlighter = strings.NewReplacer(
+	storage.HighlightStart, "<mark>",
+	storage.HighlightEnd, "</mark>",
+)
+
+// New searches links by alias, target, title, notes and tags, best
+// matches first. ?q= is required, ?limit= and ?offset= page through the
+// results.
+func New(log *slog.Logger, searcher URLSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.search.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+
+		q := strings.TrimSpace(query.Get("q"))
+		if q == "" || len(q) > maxQueryLen {
+			log.Info("invalid query", slog.Int("length", len(q)))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("q must be between 1 and "+strconv.Itoa(maxQueryLen)+" bytes long"))
+			return
+		}
+
+		limit := defaultLimit
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxLimit {
+				log.Info("invalid limit", slog.String("limit", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("limit must be between 1 and "+strconv.Itoa(maxLimit)))
+				return
+			}
+			limit = n
+		}
+
+		offset := 0
+		if v := query.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.Info("invalid offset", slog.String("offset", v))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("offset must not be negative"))
+				return
+			}
+			offset = n
+		}
+
+		found, total, err := searcher.SearchURLs(q, limit, offset)
+		if err != nil {
+			log.Error("failed to search urls", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to search urls"))
+			return
+		}
+
+		results := make([]Result, 0, len(found))
+		for _, f := range found {
+			results = append(results, Result{
+				Link: details.NewLink(f.URL),
+				Highlights: Highlights{
+					Alias: highlight(f.Alias),
+					URL:   highlight(f.Target),
+					Title: highlight(f.Title),
+					Notes: highlight(f.Notes),
+					Tags:  highlight(f.Tags),
+				},
+				Rank: f.Rank,
+			})
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Results:  results,
+			Total:    total,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+}
+
+// highlight escapes s for HTML before turning the match markers into
+// tags, so that link fields cannot inject markup.
+func highlight(s string) string {
+	return highlighter.Replace(html.EscapeString(s))
+}
@@ -0,0 +1,109 @@
+package search_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/search"
+	"url-shortener/internal/http-server/handlers/url/search/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestSearchHandler(t *testing.T) {
+	found := []storage.SearchResult{{
+		URL:    storage.URL{Domain: "go.example", Alias: "q3", URL: "https://example.com/q3", Title: "<b>Q3</b> report"},
+		Alias:  "q3",
+		Target: "https://example.com/q3",
+		Title:  "<b>Q3</b> " + storage.HighlightStart + "report" + storage.HighlightEnd,
+		Rank:   -1.5,
+	}}
+
+	cases := []struct {
+		name       string
+		query      string
+		wantSearch bool
+		wantLimit  int
+		wantOffset int
+		searchErr  error
+		wantCode   int
+		wantError  string
+	}{
+		{
+			name:       "Defaults",
+			query:      "q=report",
+			wantSearch: true,
+			wantLimit:  20,
+		},
+		{
+			name:       "Paging",
+			query:      "q=report&limit=5&offset=10",
+			wantSearch: true,
+			wantLimit:  5,
+			wantOffset: 10,
+		},
+		{
+			name:      "Empty query",
+			query:     "q=%20",
+			wantCode:  http.StatusBadRequest,
+			wantError: "q must be between 1 and 256 bytes long",
+		},
+		{
+			name:      "Invalid limit",
+			query:     "q=report&limit=101",
+			wantCode:  http.StatusBadRequest,
+			wantError: "limit must be between 1 and 100",
+		},
+		{
+			name:       "Storage error",
+			query:      "q=report",
+			wantSearch: true,
+			wantLimit:  20,
+			searchErr:  errors.New("unexpected error"),
+			wantError:  "failed to search urls",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			searcher := mocks.NewURLSearcher(t)
+			if tc.wantSearch {
+				searcher.On("SearchURLs", "report", tc.wantLimit, tc.wantOffset).
+					Return(found, 7, tc.searchErr).Once()
+			}
+
+			rr := httptest.NewRecorder()
+			search.New(slogdiscard.NewDiscardLogger(), searcher).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/search?"+tc.query, nil))
+
+			wantCode := tc.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			require.Equal(t, wantCode, rr.Code)
+
+			var body search.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+			if tc.wantError != "" {
+				return
+			}
+
+			assert.Equal(t, resp.StatusOK, body.Status)
+			assert.Equal(t, 7, body.Total)
+			assert.Equal(t, tc.wantLimit, body.Limit)
+			assert.Equal(t, tc.wantOffset, body.Offset)
+			require.Len(t, body.Results, 1)
+			assert.Equal(t, "q3", body.Results[0].Link.Alias)
+			assert.Equal(t, "&lt;b&gt;Q3&lt;/b&gt; <mark>report</mark>", body.Results[0].Highlights.Title)
+			assert.Equal(t, -1.5, body.Results[0].Rank)
+		})
+	}
+}
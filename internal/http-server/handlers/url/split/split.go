@@ -0,0 +1,94 @@
+package split
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	libsplit "url-shortener/internal/lib/split"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+// Request replaces the whole split, so weights can be changed while the
+// alias stays the same. No variants turn the split off.
+type Request = storage.Split
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=SplitSetter
+type SplitSetter interface {
+	SetSplit(domain string, alias string, split storage.Split) error
+}
+
+// New replaces the A/B split of a link.
+func New(
+	log *slog.Logger,
+	splitSetter SplitSetter,
+	allowedDomains *domains.Set,
+	policy urlpolicy.Policy,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.split.New"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty", slog.String("alias", alias))
+			render.JSON(w, r, resp.Error("alias is empty"))
+			return
+		}
+
+		domain := allowedDomains.Default()
+		if d := r.URL.Query().Get("domain"); d != "" {
+			if !allowedDomains.IsAllowed(d) {
+				log.Info("domain is not allowed", slog.String("domain", d))
+				render.JSON(w, r, resp.Error("domain is not allowed"))
+				return
+			}
+			domain = domains.Normalize(d)
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		if err := libsplit.Validate(req); err != nil {
+			log.Info("invalid split", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		if err := libsplit.CheckTargets(r.Context(), policy, req); err != nil {
+			log.Info("variant target rejected by policy", sl.Err(err))
+			render.JSON(w, r, resp.Error("url is not allowed: "+err.Error()))
+			return
+		}
+
+		if err := splitSetter.SetSplit(domain, alias, req); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to set split", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to set split"))
+			}
+			return
+		}
+
+		log.Info("split updated", slog.String("alias", alias), slog.String("domain", domain), slog.Int("variants", len(req.Variants)))
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,116 @@
+package split_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/split"
+	"url-shortener/internal/http-server/handlers/url/split/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy/urlpolicytest"
+	"url-shortener/internal/storage"
+)
+
+func TestSplitHandler(t *testing.T) {
+	const body = `{"sticky": true, "variants": [
+		{"name": "a", "target": "https://example.com/a", "weight": 80},
+		{"name": "b", "target": "https://example.com/b", "weight": 20}
+	]}`
+
+	want := storage.Split{
+		Sticky: true,
+		Variants: []storage.Variant{
+			{Name: "a", Target: "https://example.com/a", Weight: 80},
+			{Name: "b", Target: "https://example.com/b", Weight: 20},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		domain    string
+		split     storage.Split
+		setErr    error
+		wantError string
+	}{
+		{
+			name:   "Replace",
+			body:   body,
+			domain: "go.example",
+			split:  want,
+		},
+		{
+			name:   "Turn off on other domain",
+			query:  "domain=s.example",
+			body:   `{"variants": []}`,
+			domain: "s.example",
+			split:  storage.Split{Variants: []storage.Variant{}},
+		},
+		{
+			name:      "All weights zero",
+			body:      `{"variants": [{"name": "a", "target": "https://example.com/a", "weight": 0}]}`,
+			wantError: "invalid split: at least one variant needs a positive weight",
+		},
+		{
+			name:      "Target rejected by policy",
+			body:      `{"variants": [{"name": "a", "target": "http://10.0.0.1/a", "weight": 1}]}`,
+			wantError: `url is not allowed: variant "a": url points to a private network: 10.0.0.1`,
+		},
+		{
+			name:      "Not found",
+			body:      body,
+			domain:    "go.example",
+			split:     want,
+			setErr:    storage.ErrURLNotFound,
+			wantError: "url not found",
+		},
+		{
+			name:      "Storage error",
+			body:      body,
+			domain:    "go.example",
+			split:     want,
+			setErr:    errors.New("unexpected error"),
+			wantError: "failed to set split",
+		},
+	}
+
+	policy := urlpolicytest.New(t)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setter := mocks.NewSplitSetter(t)
+			if tc.domain != "" {
+				setter.On("SetSplit", tc.domain, "landing", tc.split).Return(tc.setErr).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Put("/url/{alias}/split", split.New(
+				slogdiscard.NewDiscardLogger(),
+				setter,
+				domains.New("go.example", []string{"s.example"}),
+				policy,
+			))
+
+			req := httptest.NewRequest(http.MethodPut, "/url/landing/split?"+tc.query, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+}
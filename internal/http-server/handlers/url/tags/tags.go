@@ -0,0 +1,176 @@
+package tags
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	libtags "url-shortener/internal/lib/tags"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	// Tags are added to the ones the link already has.
+	Tags []string `json:"tags"`
+}
+
+type ListResponse struct {
+	resp.Response
+	Tags []storage.TagCount `json:"tags"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TagAdder
+type TagAdder interface {
+	AddTags(domain string, alias string, tags []string) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TagRemover
+type TagRemover interface {
+	RemoveTag(domain string, alias string, tag string) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TagLister
+type TagLister interface {
+	ListTags() ([]storage.TagCount, error)
+}
+
+// NewAdd adds tags to a link.
+func NewAdd(log *slog.Logger, adder TagAdder, allowedDomains *domains.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.tags.NewAdd"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias, domain, ok := target(w, r, log, allowedDomains)
+		if !ok {
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("invalid request body"))
+			return
+		}
+
+		tags, err := libtags.Normalize(req.Tags)
+		if err != nil {
+			log.Info("invalid tags", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+		if len(tags) == 0 {
+			log.Info("no tags given")
+			render.JSON(w, r, resp.Error("tags are empty"))
+			return
+		}
+
+		if err := adder.AddTags(domain, alias, tags); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to add tags", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to add tags"))
+			}
+			return
+		}
+
+		log.Info("tags added", slog.String("alias", alias), slog.String("domain", domain), slog.Any("tags", tags))
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// NewRemove removes the tag in the path from a link.
+func NewRemove(log *slog.Logger, remover TagRemover, allowedDomains *domains.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.tags.NewRemove"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias, domain, ok := target(w, r, log, allowedDomains)
+		if !ok {
+			return
+		}
+
+		tags, err := libtags.Normalize([]string{chi.URLParam(r, "tag")})
+		if err != nil {
+			log.Info("invalid tag", sl.Err(err))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		if err := remover.RemoveTag(domain, alias, tags[0]); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrURLNotFound):
+				log.Info("url not found", slog.String("alias", alias), slog.String("domain", domain))
+				render.JSON(w, r, resp.Error("url not found"))
+			default:
+				log.Error("failed to remove tag", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to remove tag"))
+			}
+			return
+		}
+
+		log.Info("tag removed", slog.String("alias", alias), slog.String("domain", domain), slog.String("tag", tags[0]))
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// NewList lists all tags in use with the number of links per tag.
+func NewList(log *slog.Logger, lister TagLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.tags.NewList"
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		tags, err := lister.ListTags()
+		if err != nil {
+			log.Error("failed to list tags", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to list tags"))
+			return
+		}
+
+		render.JSON(w, r, ListResponse{
+			Response: resp.OK(),
+			Tags:     tags,
+		})
+	}
+}
+
+// target reads the alias from the path and the domain from the query,
+// writing the error response itself when either is invalid.
+func target(w http.ResponseWriter, r *http.Request, log *slog.Logger, allowedDomains *domains.Set) (string, string, bool) {
+	alias := chi.URLParam(r, "alias")
+	if alias == "" {
+		log.Info("alias is empty", slog.String("alias", alias))
+		render.JSON(w, r, resp.Error("alias is empty"))
+		return "", "", false
+	}
+
+	domain := allowedDomains.Default()
+	if d := r.URL.Query().Get("domain"); d != "" {
+		if !allowedDomains.IsAllowed(d) {
+			log.Info("domain is not allowed", slog.String("domain", d))
+			render.JSON(w, r, resp.Error("domain is not allowed"))
+			return "", "", false
+		}
+		domain = domains.Normalize(d)
+	}
+
+	return alias, domain, true
+}
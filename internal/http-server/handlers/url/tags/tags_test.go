@@ -0,0 +1,156 @@
+package tags_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/tags"
+	"url-shortener/internal/http-server/handlers/url/tags/mocks"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestAddHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		query     string
+		body      string
+		domain    string
+		tags      []string
+		addErr    error
+		wantError string
+	}{
+		{
+			name:   "Add",
+			body:   `{"tags": ["Spring-Sale", "team:growth"]}`,
+			domain: "go.example",
+			tags:   []string{"spring-sale", "team:growth"},
+		},
+		{
+			name:   "Other domain",
+			query:  "domain=s.example",
+			body:   `{"tags": ["docs"]}`,
+			domain: "s.example",
+			tags:   []string{"docs"},
+		},
+		{
+			name:      "Invalid tag",
+			body:      `{"tags": ["a b"]}`,
+			wantError: `invalid tag: "a b"`,
+		},
+		{
+			name:      "No tags",
+			body:      `{"tags": []}`,
+			wantError: "tags are empty",
+		},
+		{
+			name:      "Invalid body",
+			body:      `{`,
+			wantError: "invalid request body",
+		},
+		{
+			name:      "Not found",
+			body:      `{"tags": ["docs"]}`,
+			domain:    "go.example",
+			tags:      []string{"docs"},
+			addErr:    storage.ErrURLNotFound,
+			wantError: "url not found",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"tags": ["docs"]}`,
+			domain:    "go.example",
+			tags:      []string{"docs"},
+			addErr:    errors.New("unexpected error"),
+			wantError: "failed to add tags",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			adder := mocks.NewTagAdder(t)
+			if tc.domain != "" {
+				adder.On("AddTags", tc.domain, "app", tc.tags).Return(tc.addErr).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Post("/url/{alias}/tags", tags.NewAdd(
+				slogdiscard.NewDiscardLogger(),
+				adder,
+				domains.New("go.example", []string{"s.example"}),
+			))
+
+			req := httptest.NewRequest(http.MethodPost, "/url/app/tags?"+tc.query, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+}
+
+func TestRemoveHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		tag       string
+		wantTag   string
+		removeErr error
+		wantError string
+	}{
+		{name: "Remove", tag: "Docs", wantTag: "docs"},
+		{name: "Invalid tag", tag: "-docs", wantError: `invalid tag: "-docs"`},
+		{name: "Not found", tag: "docs", wantTag: "docs", removeErr: storage.ErrURLNotFound, wantError: "url not found"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			remover := mocks.NewTagRemover(t)
+			if tc.wantTag != "" {
+				remover.On("RemoveTag", "go.example", "app", tc.wantTag).Return(tc.removeErr).Once()
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/url/{alias}/tags/{tag}", tags.NewRemove(
+				slogdiscard.NewDiscardLogger(),
+				remover,
+				domains.New("go.example", nil),
+			))
+
+			req := httptest.NewRequest(http.MethodDelete, "/url/app/tags/"+tc.tag, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantError, body.Error)
+		})
+	}
+}
+
+func TestListHandler(t *testing.T) {
+	lister := mocks.NewTagLister(t)
+	lister.On("ListTags").Return([]storage.TagCount{{Name: "docs", Count: 2}}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	tags.NewList(slogdiscard.NewDiscardLogger(), lister).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/tags", nil))
+
+	var body tags.ListResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, resp.StatusOK, body.Status)
+	assert.Equal(t, []storage.TagCount{{Name: "docs", Count: 2}}, body.Tags)
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// adminCacheSweepThreshold is the number of cached users from which
+	// expired entries are dropped, at most once per adminCacheSweepInterval.
+	adminCacheSweepThreshold = 1024
+	adminCacheSweepInterval  = time.Second
+)
+
+type AdminCacheOptions struct {
+	// PositiveTTL is how long a user stays an admin without asking SSO
+	// again, i.e. how long a revoked admin keeps their rights.
+	PositiveTTL time.Duration
+	// NegativeTTL is how long a user stays a non-admin, i.e. how long a
+	// new admin waits for their rights. Zero TTLs disable caching.
+	NegativeTTL time.Duration
+	// StaleFor keeps serving an expired decision for this long after it
+	// expired while SSO fails, e.g. while its circuit breaker is open.
+	// Zero fails such checks instead.
+	StaleFor time.Duration
+}
+
+// AdminCacheStats counts IsAdmin calls of a CachedAdminChecker.
+type AdminCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Checks are the calls to SSO; misses beyond them waited for the
+	// check of a concurrent call.
+	Checks int64 `json:"checks"`
+	Errors int64 `json:"errors"`
+	// Stale errors were answered with an expired decision.
+	Stale   int64 `json:"stale"`
+	Entries int   `json:"entries"`
+	// HitRate is Hits over all calls; zero before the first call.
+	HitRate float64 `json:"hit_rate"`
+}
+
+// CachedAdminChecker remembers the decisions of another AdminChecker.
+// Concurrent checks of the same user share one call; errors are not
+// cached.
+type CachedAdminChecker struct {
+	next AdminChecker
+	opts AdminCacheOptions
+
+	mu        sync.Mutex
+	entries   map[int64]adminEntry
+	lastSweep time.Time
+	// generation is bumped by invalidation so that checks started
+	// before it do not store their result afterwards.
+	generation uint64
+
+	group singleflight.Group
+	now   func() time.Time
+
+	hits, misses, checks, errors, stale atomic.Int64
+}
+
+type adminEntry struct {
+	isAdmin bool
+	expires time.Time
+}
+
+func NewCachedAdminChecker(next AdminChecker, opts AdminCacheOptions) *CachedAdminChecker {
+	return &CachedAdminChecker{
+		next:    next,
+		opts:    opts,
+		entries: make(map[int64]adminEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *CachedAdminChecker) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	c.mu.Lock()
+	e, ok := c.entries[userID]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		c.hits.Add(1)
+		return e.isAdmin, nil
+	}
+	c.misses.Add(1)
+
+	// The shared check must not fail because the caller that started it
+	// went away, but it keeps the caller's deadline.
+	deadline, hasDeadline := ctx.Deadline()
+	checkCtx := context.WithoutCancel(ctx)
+	ch := c.group.DoChan(strconv.FormatInt(userID, 10), func() (any, error) {
+		ctx := checkCtx
+		if hasDeadline {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(checkCtx, deadline)
+			defer cancel()
+		}
+
+		c.checks.Add(1)
+		isAdmin, err := c.next.IsAdmin(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		c.store(userID, isAdmin, generation)
+		return isAdmin, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			c.errors.Add(1)
+			if isAdmin, ok := c.lookupStale(userID); ok {
+				c.stale.Add(1)
+				return isAdmin, nil
+			}
+			return false, res.Err
+		}
+		return res.Val.(bool), nil
+	}
+}
+
+func (c *CachedAdminChecker) store(userID int64, isAdmin bool, generation uint64) {
+	ttl := c.opts.NegativeTTL
+	if isAdmin {
+		ttl = c.opts.PositiveTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := c.now()
+	if len(c.entries) >= adminCacheSweepThreshold && now.Sub(c.lastSweep) >= adminCacheSweepInterval {
+		for id, e := range c.entries {
+			if !now.Before(e.expires.Add(c.opts.StaleFor)) {
+				delete(c.entries, id)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	c.entries[userID] = adminEntry{isAdmin: isAdmin, expires: now.Add(ttl)}
+}
+
+func (c *CachedAdminChecker) lookupStale(userID int64) (bool, bool) {
+	if c.opts.StaleFor <= 0 {
+		return false, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[userID]
+	if !ok || !c.now().Before(e.expires.Add(c.opts.StaleFor)) {
+		return false, false
+	}
+	return e.isAdmin, true
+}
+
+// Invalidate forgets the decision about one user, e.g. after their
+// rights changed in SSO.
+func (c *CachedAdminChecker) Invalidate(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userID)
+	c.generation++
+}
+
+// InvalidateAll forgets every decision, including those of checks in
+// flight.
+func (c *CachedAdminChecker) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[int64]adminEntry)
+	c.generation++
+}
+
+func (c *CachedAdminChecker) Stats() AdminCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	s := AdminCacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Checks:  c.checks.Load(),
+		Errors:  c.errors.Load(),
+		Stale:   c.stale.Load(),
+		Entries: entries,
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingChecker struct {
+	calls   atomic.Int32
+	admins  map[int64]bool
+	err     error
+	release chan struct{}
+}
+
+func (c *countingChecker) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	c.calls.Add(1)
+	if c.release != nil {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	return c.admins[userID], c.err
+}
+
+func TestCachedAdminChecker_TTLs(t *testing.T) {
+	next := &countingChecker{admins: map[int64]bool{1: true}}
+	c := NewCachedAdminChecker(next, AdminCacheOptions{PositiveTTL: time.Minute, NegativeTTL: 10 * time.Second})
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+
+	for range 3 {
+		isAdmin, err := c.IsAdmin(context.Background(), 1)
+		require.NoError(t, err)
+		assert.True(t, isAdmin)
+
+		isAdmin, err = c.IsAdmin(context.Background(), 2)
+		require.NoError(t, err)
+		assert.False(t, isAdmin)
+	}
+	assert.EqualValues(t, 2, next.calls.Load())
+
+	// The negative answer expires first.
+	now = now.Add(30 * time.Second)
+	_, _ = c.IsAdmin(context.Background(), 1)
+	_, _ = c.IsAdmin(context.Background(), 2)
+	assert.EqualValues(t, 3, next.calls.Load())
+
+	s := c.Stats()
+	assert.EqualValues(t, 5, s.Hits)
+	assert.EqualValues(t, 3, s.Misses)
+	assert.Equal(t, 2, s.Entries)
+	assert.InDelta(t, 5.0/8, s.HitRate, 1e-9)
+}
+
+func TestCachedAdminChecker_ErrorsAreNotCached(t *testing.T) {
+	next := &countingChecker{err: errors.New("sso unavailable")}
+	c := NewCachedAdminChecker(next, AdminCacheOptions{PositiveTTL: time.Minute, NegativeTTL: time.Minute})
+
+	for range 2 {
+		_, err := c.IsAdmin(context.Background(), 1)
+		require.Error(t, err)
+	}
+	assert.EqualValues(t, 2, next.calls.Load())
+	assert.EqualValues(t, 2, c.Stats().Errors)
+}
+
+func TestCachedAdminChecker_Invalidate(t *testing.T) {
+	next := &countingChecker{admins: map[int64]bool{1: true, 2: true}}
+	c := NewCachedAdminChecker(next, AdminCacheOptions{PositiveTTL: time.Hour})
+
+	_, _ = c.IsAdmin(context.Background(), 1)
+	_, _ = c.IsAdmin(context.Background(), 2)
+
+	c.Invalidate(1)
+	_, _ = c.IsAdmin(context.Background(), 1)
+	_, _ = c.IsAdmin(context.Background(), 2)
+	assert.EqualValues(t, 3, next.calls.Load())
+
+	c.InvalidateAll()
+	_, _ = c.IsAdmin(context.Background(), 2)
+	assert.EqualValues(t, 4, next.calls.Load())
+}
+
+func TestCachedAdminChecker_ConcurrentChecksShareOneCall(t *testing.T) {
+	next := &countingChecker{admins: map[int64]bool{1: true}, release: make(chan struct{})}
+	c := NewCachedAdminChecker(next, AdminCacheOptions{PositiveTTL: time.Minute})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			isAdmin, err := c.IsAdmin(context.Background(), 1)
+			assert.NoError(t, err)
+			assert.True(t, isAdmin)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return c.Stats().Misses == callers }, time.Second, time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, next.calls.Load())
+	assert.EqualValues(t, 1, c.Stats().Checks)
+}
+
+func TestCachedAdminChecker_CallerGivingUpDoesNotFailOthers(t *testing.T) {
+	next := &countingChecker{admins: map[int64]bool{1: true}, release: make(chan struct{})}
+	c := NewCachedAdminChecker(next, AdminCacheOptions{PositiveTTL: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.IsAdmin(ctx, 1)
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan bool, 1)
+	go func() {
+		isAdmin, _ := c.IsAdmin(context.Background(), 1)
+		second <- isAdmin
+	}()
+
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+
+	close(next.release)
+	assert.True(t, <-second)
+	assert.EqualValues(t, 1, next.calls.Load())
+}
+
+func TestCachedAdminChecker_ServeStale(t *testing.T) {
+	next := &countingChecker{admins: map[int64]bool{1: true}}
+	c := NewCachedAdminChecker(next, AdminCacheOptions{PositiveTTL: time.Minute, StaleFor: time.Hour})
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+
+	_, err := c.IsAdmin(context.Background(), 1)
+	require.NoError(t, err)
+
+	next.err = errors.New("sso unavailable")
+	now = now.Add(30 * time.Minute)
+
+	isAdmin, err := c.IsAdmin(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, isAdmin)
+	assert.EqualValues(t, 1, c.Stats().Stale)
+
+	// Users never seen before, and decisions older than StaleFor, fail.
+	_, err = c.IsAdmin(context.Background(), 2)
+	require.Error(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = c.IsAdmin(context.Background(), 1)
+	require.Error(t, err)
+}
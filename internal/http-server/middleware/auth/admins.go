@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// StaticAdmins are admins listed in the config, for deployments without
+// an SSO service.
+type StaticAdmins map[int64]struct{}
+
+func NewStaticAdmins(userIDs []int64) StaticAdmins {
+	a := make(StaticAdmins, len(userIDs))
+	for _, id := range userIDs {
+		a[id] = struct{}{}
+	}
+	return a
+}
+
+func (a StaticAdmins) IsAdmin(_ context.Context, userID int64) (bool, error) {
+	_, ok := a[userID]
+	return ok, nil
+}
+
+const (
+	DefaultRolesClaim = "roles"
+	DefaultAdminRole  = "admin"
+	DefaultAdminClaim = "is_admin"
+)
+
+type ClaimAdminsOptions struct {
+	// RolesClaim names a claim with a list of roles, or a single
+	// space-separated string of them. Defaults to DefaultRolesClaim.
+	RolesClaim string
+	// AdminRole is the role in RolesClaim that makes an admin. Defaults
+	// to DefaultAdminRole.
+	AdminRole string
+	// AdminClaim names a boolean claim that makes an admin when true.
+	// Defaults to DefaultAdminClaim.
+	AdminClaim string
+}
+
+// ClaimAdmins trusts the bearer JWT of the request to say whether its
+// user is an admin. Requests without one, such as those authenticated
+// with an API key, are not admins.
+type ClaimAdmins struct {
+	opts ClaimAdminsOptions
+}
+
+func NewClaimAdmins(opts ClaimAdminsOptions) *ClaimAdmins {
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = DefaultRolesClaim
+	}
+	if opts.AdminRole == "" {
+		opts.AdminRole = DefaultAdminRole
+	}
+	if opts.AdminClaim == "" {
+		opts.AdminClaim = DefaultAdminClaim
+	}
+	return &ClaimAdmins{opts: opts}
+}
+
+func (a *ClaimAdmins) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	token, ok := TokenFromContext(ctx)
+	if !ok || token.UserID != userID {
+		return false, nil
+	}
+
+	if isAdmin, ok := token.Claims[a.opts.AdminClaim].(bool); ok && isAdmin {
+		return true, nil
+	}
+
+	switch roles := token.Claims[a.opts.RolesClaim].(type) {
+	case []any:
+		for _, r := range roles {
+			if r == a.opts.AdminRole {
+				return true, nil
+			}
+		}
+	case string:
+		for _, r := range strings.Fields(roles) {
+			if r == a.opts.AdminRole {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// AnyAdmins makes admins of the users any of its checkers says are. The
+// checkers are asked in order until one says yes, so put the cheap ones
+// first. Errors are returned only when no checker says yes.
+type AnyAdmins []AdminChecker
+
+func (a AnyAdmins) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	var errs []error
+	for _, c := range a {
+		isAdmin, err := c.IsAdmin(ctx, userID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if isAdmin {
+			return true, nil
+		}
+	}
+	return false, errors.Join(errs...)
+}
+
+// WithToken returns ctx carrying the verified bearer JWT of the request,
+// for ClaimAdmins.
+func WithToken(ctx context.Context, token Token) context.Context {
+	return context.WithValue(ctx, ctxTokenKey, token)
+}
+
+// TokenFromContext returns the bearer JWT a request was authenticated
+// with. It is false for requests authenticated with an API key.
+func TokenFromContext(ctx context.Context) (Token, bool) {
+	t, ok := ctx.Value(ctxTokenKey).(Token)
+	return t, ok
+}
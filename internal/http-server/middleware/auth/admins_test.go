@@ -0,0 +1,149 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/breaker"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestStaticAdmins(t *testing.T) {
+	a := auth.NewStaticAdmins([]int64{1, 2})
+
+	isAdmin, err := a.IsAdmin(context.Background(), 2)
+	require.NoError(t, err)
+	assert.True(t, isAdmin)
+
+	isAdmin, err = a.IsAdmin(context.Background(), 3)
+	require.NoError(t, err)
+	assert.False(t, isAdmin)
+}
+
+func TestClaimAdmins(t *testing.T) {
+	a := auth.NewClaimAdmins(auth.ClaimAdminsOptions{})
+
+	cases := []struct {
+		name   string
+		userID int64
+		claims jwt.MapClaims
+		noJWT  bool
+		want   bool
+	}{
+		{name: "Roles list", userID: 1, claims: jwt.MapClaims{"roles": []any{"editor", "admin"}}, want: true},
+		{name: "Roles string", userID: 1, claims: jwt.MapClaims{"roles": "editor admin"}, want: true},
+		{name: "Other roles", userID: 1, claims: jwt.MapClaims{"roles": []any{"editor"}}},
+		{name: "Flag", userID: 1, claims: jwt.MapClaims{"is_admin": true}, want: true},
+		{name: "Flag false", userID: 1, claims: jwt.MapClaims{"is_admin": false}},
+		{name: "Flag as string", userID: 1, claims: jwt.MapClaims{"is_admin": "true"}},
+		{name: "Token of another user", userID: 2, claims: jwt.MapClaims{"is_admin": true}},
+		{name: "No token", userID: 1, noJWT: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if !tc.noJWT {
+				ctx = auth.WithToken(ctx, auth.Token{UserID: 1, Claims: tc.claims})
+			}
+
+			isAdmin, err := a.IsAdmin(ctx, tc.userID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, isAdmin)
+		})
+	}
+}
+
+func TestClaimAdmins_CustomClaims(t *testing.T) {
+	a := auth.NewClaimAdmins(auth.ClaimAdminsOptions{RolesClaim: "groups", AdminRole: "ops"})
+	ctx := auth.WithToken(context.Background(), auth.Token{UserID: 1, Claims: jwt.MapClaims{"groups": []any{"ops"}}})
+
+	isAdmin, err := a.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, isAdmin)
+}
+
+func TestAnyAdmins(t *testing.T) {
+	sso := adminFunc(func(_ context.Context, userID int64) (bool, error) {
+		if userID == 3 || userID == 5 {
+			return false, &breaker.OpenError{RetryAfter: time.Second}
+		}
+		return userID == 2, nil
+	})
+	a := auth.AnyAdmins{auth.NewStaticAdmins([]int64{1, 3}), sso}
+
+	cases := []struct {
+		name    string
+		userID  int64
+		want    bool
+		wantErr bool
+	}{
+		{name: "Static", userID: 1, want: true},
+		{name: "SSO", userID: 2, want: true},
+		{name: "Neither", userID: 4},
+		{name: "SSO down for a static admin", userID: 3, want: true},
+		{name: "SSO down", userID: 5, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			isAdmin, err := a.IsAdmin(context.Background(), tc.userID)
+			if tc.wantErr {
+				require.ErrorIs(t, err, breaker.ErrOpen)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, isAdmin)
+		})
+	}
+}
+
+type adminFunc func(ctx context.Context, userID int64) (bool, error)
+
+func (f adminFunc) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	return f(ctx, userID)
+}
+
+type claimsVerifier jwt.MapClaims
+
+func (c claimsVerifier) Verify(context.Context, string) (auth.Token, error) {
+	return auth.Token{UserID: 7, Claims: jwt.MapClaims(c)}, nil
+}
+
+func TestAuthenticate_ClaimAdmins(t *testing.T) {
+	roles := auth.NewRoles(auth.NewClaimAdmins(auth.ClaimAdminsOptions{}), auth.RoleViewer, nil)
+
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   auth.Role
+	}{
+		{name: "Admin claim", claims: jwt.MapClaims{"roles": []any{"admin"}}, want: auth.RoleAdmin},
+		{name: "No admin claim", claims: jwt.MapClaims{}, want: auth.RoleViewer},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got auth.Principal
+			h := auth.Authenticate(slogdiscard.NewDiscardLogger(), roles, nil, claimsVerifier(tc.claims), time.Second)(
+				http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					got, _ = auth.PrincipalFromContext(r.Context())
+				}),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/url/", nil)
+			req.Header.Set("Authorization", "Bearer token")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tc.want, got.Role)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/breaker"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// APIKeyStore looks up the keys sent in the X-API-Key header.
+type APIKeyStore interface {
+	APIKeyByHash(hash string) (storage.APIKey, error)
+	TouchAPIKey(id int64, usedAt time.Time) error
+}
+
+// Principal is who a request is made by.
+type Principal struct {
+	UserID int64
+	Role   Role
+	// APIKey is set when the request was authenticated with an API key,
+	// which acts on behalf of its owner, UserID.
+	APIKey *storage.APIKey
+}
+
+type ctxKey string
+
+const (
+	ctxPrincipalKey ctxKey = "principal"
+	ctxTokenKey     ctxKey = "token"
+)
+
+// APIKeyHeader carries an API key as an alternative to a bearer JWT.
+const APIKeyHeader = "X-API-Key"
+
+// WithPrincipal returns ctx carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxPrincipalKey, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxPrincipalKey).(Principal)
+	return p, ok
+}
+
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	return p.UserID, ok
+}
+
+// APIKeyFromContext returns the key a request was authenticated with.
+// It is false for requests authenticated with a JWT.
+func APIKeyFromContext(ctx context.Context) (storage.APIKey, bool) {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok || p.APIKey == nil {
+		return storage.APIKey{}, false
+	}
+	return *p.APIKey, true
+}
+
+// Authenticate lets in any user with a bearer JWT or an API key in
+// X-API-Key and stores their Principal in the context. What they may do
+// is checked by Require, OwnerOnly and RequireScope.
+func Authenticate(
+	log *slog.Logger,
+	roles RoleResolver,
+	apiKeys APIKeyStore,
+	tokens TokenVerifier,
+	ssoTimeout time.Duration,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.Authenticate"
+
+			log := log.With(
+				slog.String("op", op),
+			)
+
+			if key := r.Header.Get(APIKeyHeader); key != "" {
+				authenticateAPIKey(w, r, log, next, roles, apiKeys, key, ssoTimeout)
+				return
+			}
+
+			token, err := bearerToken(r.Header.Get("Authorization"))
+			if err != nil {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("unauthorized"))
+				return
+			}
+
+			verified, err := tokens.Verify(r.Context(), token)
+			if err != nil {
+				log.Info("token rejected", slog.String("reason", RejectionReason(err)), sl.Err(err))
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("unauthorized"))
+				return
+			}
+
+			log.Debug("token verified",
+				slog.Int64("user_id", verified.UserID),
+				slog.String("key_id", verified.KeyID),
+			)
+
+			r = r.WithContext(WithToken(r.Context(), verified))
+			role, ok := resolveRole(w, r, log, roles, verified.UserID, ssoTimeout)
+			if !ok {
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), Principal{UserID: verified.UserID, Role: role})))
+		})
+	}
+}
+
+// resolveRole writes the error response itself when it fails.
+func resolveRole(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	roles RoleResolver,
+	userID int64,
+	ssoTimeout time.Duration,
+) (Role, bool) {
+	ctx := r.Context()
+	if ssoTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ssoTimeout)
+		defer cancel()
+	}
+
+	role, err := roles.Role(ctx, userID)
+	var openErr *breaker.OpenError
+	if errors.As(err, &openErr) {
+		log.Warn("sso is unavailable", slog.Int64("user_id", userID), slog.Duration("retry_after", openErr.RetryAfter))
+		retryAfter := int(math.Ceil(openErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, resp.Error("auth service unavailable"))
+		return "", false
+	}
+	if err != nil {
+		log.Error("failed to resolve role", sl.Err(err), slog.Int64("user_id", userID))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error("internal error"))
+		return "", false
+	}
+
+	return role, true
+}
+
+func authenticateAPIKey(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	next http.Handler,
+	roles RoleResolver,
+	apiKeys APIKeyStore,
+	key string,
+	ssoTimeout time.Duration,
+) {
+	if apiKeys == nil {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Error("unauthorized"))
+		return
+	}
+
+	k, err := apiKeys.APIKeyByHash(apikey.Hash(key))
+	if err != nil {
+		if !errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Error("failed to look up api key", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+		log.Info("unknown api key")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Error("unauthorized"))
+		return
+	}
+	if !k.RevokedAt.IsZero() {
+		log.Info("revoked api key", slog.Int64("api_key_id", k.ID))
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, resp.Error("unauthorized"))
+		return
+	}
+
+	role, ok := resolveRole(w, r, log, roles, k.OwnerID, ssoTimeout)
+	if !ok {
+		return
+	}
+
+	// A failed write only makes the last use less precise.
+	if err := apiKeys.TouchAPIKey(k.ID, time.Now()); err != nil {
+		log.Error("failed to record api key use", sl.Err(err), slog.Int64("api_key_id", k.ID))
+	}
+
+	next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), Principal{UserID: k.OwnerID, Role: role, APIKey: &k})))
+}
+
+// RequireScope rejects requests made with an API key lacking scope.
+// Requests authenticated with a JWT are not restricted.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k, ok := APIKeyFromContext(r.Context()); ok && !apikey.HasScope(k.Scopes, scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("api key lacks scope "+scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UsersOnly rejects requests made with an API key, e.g. to keep keys
+// from managing keys.
+func UsersOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := APIKeyFromContext(r.Context()); ok {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error("forbidden"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(authorization string) (string, error) {
+	authorization = strings.TrimSpace(authorization)
+	if authorization == "" {
+		return "", errors.New("authorization header is empty")
+	}
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authorization, prefix) {
+		return "", errors.New("authorization header is not bearer")
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(authorization, prefix))
+	if token == "" {
+		return "", errors.New("bearer token is empty")
+	}
+	return token, nil
+}
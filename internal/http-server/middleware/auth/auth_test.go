@@ -0,0 +1,48 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/breaker"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type roleFunc func(ctx context.Context, userID int64) (auth.Role, error)
+
+func (f roleFunc) Role(ctx context.Context, userID int64) (auth.Role, error) {
+	return f(ctx, userID)
+}
+
+type staticVerifier struct{}
+
+func (staticVerifier) Verify(context.Context, string) (auth.Token, error) {
+	return auth.Token{UserID: 7}, nil
+}
+
+func TestAuthenticate_SSOUnavailable(t *testing.T) {
+	roles := roleFunc(func(context.Context, int64) (auth.Role, error) {
+		return "", fmt.Errorf("check admin status: %w", &breaker.OpenError{RetryAfter: 1500 * time.Millisecond})
+	})
+	h := auth.Authenticate(slogdiscard.NewDiscardLogger(), roles, nil, staticVerifier{}, time.Second)(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			t.Fatal("must not be called")
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/url/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+}
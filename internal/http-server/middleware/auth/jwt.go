@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"url-shortener/internal/lib/hmackeys"
+	"url-shortener/internal/lib/jwks"
+)
+
+// DefaultUserIDClaim holds the user ID unless JWTOptions say otherwise.
+const DefaultUserIDClaim = "user_id"
+
+var ErrInvalidUserID = errors.New("token has no valid user id")
+
+// Token is a verified bearer JWT.
+type Token struct {
+	UserID int64
+	// KeyID identifies the key that verified the token.
+	KeyID  string
+	Claims jwt.MapClaims
+}
+
+// TokenVerifier checks the signature and claims of a bearer JWT.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (Token, error)
+}
+
+// HMACKeySet finds the secret an HS256 token was signed with and its
+// ID, see hmackeys.Source.
+type HMACKeySet interface {
+	Key(kid string) (string, []byte, error)
+}
+
+// KeySet finds the public key a token was signed with, see jwks.Source.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (jwks.Key, error)
+}
+
+// asymmetricMethods are accepted for tokens verified with a KeySet.
+var asymmetricMethods = []string{
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+type JWTOptions struct {
+	// HMACKeys enables HS256 tokens signed with shared secrets, whose kid
+	// header selects the secret.
+	HMACKeys HMACKeySet
+	// HMACSecret enables HS256 tokens signed with a single shared secret
+	// when HMACKeys is nil.
+	HMACSecret []byte
+	// Keys enables RS256, ES256 and EdDSA tokens, whose kid header
+	// selects the key.
+	Keys KeySet
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew with the issuer in exp, nbf and iat.
+	Leeway time.Duration
+	// UserIDClaim names the claim with the numeric user ID, given as a
+	// number or a string. Defaults to DefaultUserIDClaim.
+	UserIDClaim string
+	// Revocations, when set, rejects revoked tokens.
+	Revocations RevocationChecker
+}
+
+// JWTVerifier accepts HS256 tokens, asymmetrically signed ones or both.
+// Every token must expire.
+type JWTVerifier struct {
+	hmacKeys    HMACKeySet
+	keys        KeySet
+	parser      *jwt.Parser
+	userIDClaim string
+	revocations RevocationChecker
+}
+
+func NewJWTVerifier(opts JWTOptions) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		hmacKeys:    opts.HMACKeys,
+		keys:        opts.Keys,
+		userIDClaim: opts.UserIDClaim,
+		revocations: opts.Revocations,
+	}
+	if v.userIDClaim == "" {
+		v.userIDClaim = DefaultUserIDClaim
+	}
+
+	if v.hmacKeys == nil && len(opts.HMACSecret) > 0 {
+		v.hmacKeys = singleSecret(opts.HMACSecret)
+	}
+
+	var methods []string
+	if v.hmacKeys != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if v.keys != nil {
+		methods = append(methods, asymmetricMethods...)
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth.NewJWTVerifier: neither an HMAC secret nor a key set is configured")
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	v.parser = jwt.NewParser(parserOpts...)
+
+	return v, nil
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, tokenString string) (Token, error) {
+	var keyID string
+	token, err := v.parser.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			id, secret, err := v.hmacKeys.Key(kid)
+			if err != nil {
+				return nil, err
+			}
+			keyID = id
+			return secret, nil
+		}
+
+		key, err := v.keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		keyID = key.ID
+		// Without this, a token could pick another algorithm for a key
+		// than the one its issuer uses it with.
+		if key.Algorithm != "" && key.Algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("key %q is for %s, not %s", kid, key.Algorithm, token.Method.Alg())
+		}
+		return key.Public, nil
+	})
+	if err != nil {
+		return Token{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return Token{}, errors.New("invalid token")
+	}
+
+	userID, ok := asInt64(claims[v.userIDClaim])
+	if !ok || userID <= 0 {
+		return Token{}, fmt.Errorf("%w in claim %q", ErrInvalidUserID, v.userIDClaim)
+	}
+
+	verified := Token{UserID: userID, KeyID: keyID, Claims: claims}
+	if v.revocations != nil {
+		if err := v.revocations.Check(verified); err != nil {
+			return Token{}, err
+		}
+	}
+
+	return verified, nil
+}
+
+// singleSecret verifies every HS256 token with one secret, whatever its
+// kid.
+type singleSecret []byte
+
+func (s singleSecret) Key(string) (string, []byte, error) {
+	return "", s, nil
+}
+
+// RejectionReason names why Verify rejected a token, for logs.
+func RejectionReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, jwks.ErrKeyNotFound), errors.Is(err, hmackeys.ErrKeyNotFound):
+		return "unknown signing key"
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "unverifiable"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "invalid signature"
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "missing required claim"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "not valid yet"
+	case errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "issued in the future"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "wrong issuer"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "wrong audience"
+	case errors.Is(err, ErrInvalidUserID):
+		return "invalid user id"
+	case errors.Is(err, ErrTokenRevoked):
+		return "revoked"
+	default:
+		return "invalid"
+	}
+}
+
+func asInt64(v any) (int64, bool) {
+	switch vv := v.(type) {
+	case int64:
+		return vv, true
+	case int32:
+		return int64(vv), true
+	case int:
+		return int64(vv), true
+	case float64:
+		// JSON numbers decode as float64.
+		if vv != float64(int64(vv)) {
+			return 0, false
+		}
+		return int64(vv), true
+	case json.Number:
+		n, err := vv.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(vv, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
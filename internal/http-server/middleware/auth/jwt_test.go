@@ -0,0 +1,331 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/hmackeys"
+	"url-shortener/internal/lib/jwks"
+)
+
+type keySet map[string]jwks.Key
+
+func (s keySet) Key(_ context.Context, kid string) (jwks.Key, error) {
+	k, ok := s[kid]
+	if !ok {
+		return jwks.Key{}, jwks.ErrKeyNotFound
+	}
+	return k, nil
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"user_id": 42,
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keys := keySet{
+		"rsa": {ID: "rsa", Algorithm: "RS256", Public: rsaKey.Public()},
+		"ec":  {ID: "ec", Public: ecKey.Public()},
+		"ed":  {ID: "ed", Algorithm: "EdDSA", Public: edPub},
+		// Restricted to RS256, so an ES256 token must not use it.
+		"rsa-only": {ID: "rsa-only", Algorithm: "RS256", Public: ecKey.Public()},
+	}
+	secret := []byte("dev-secret")
+
+	cases := []struct {
+		name    string
+		opts    auth.JWTOptions
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "RS256",
+			opts:  auth.JWTOptions{Keys: keys},
+			token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey),
+		},
+		{
+			name:  "ES256",
+			opts:  auth.JWTOptions{Keys: keys},
+			token: sign(t, jwt.SigningMethodES256, "ec", ecKey),
+		},
+		{
+			name:  "EdDSA",
+			opts:  auth.JWTOptions{Keys: keys},
+			token: sign(t, jwt.SigningMethodEdDSA, "ed", crypto.Signer(edKey)),
+		},
+		{
+			name:  "HS256 next to a key set",
+			opts:  auth.JWTOptions{HMACSecret: secret, Keys: keys},
+			token: sign(t, jwt.SigningMethodHS256, "", secret),
+		},
+		{
+			name:    "HS256 disabled",
+			opts:    auth.JWTOptions{Keys: keys},
+			token:   sign(t, jwt.SigningMethodHS256, "", secret),
+			wantErr: true,
+		},
+		{
+			name:    "Asymmetric without a key set",
+			opts:    auth.JWTOptions{HMACSecret: secret},
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", rsaKey),
+			wantErr: true,
+		},
+		{
+			name:    "Wrong key",
+			opts:    auth.JWTOptions{Keys: keys},
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", otherRSA),
+			wantErr: true,
+		},
+		{
+			name:    "Unknown kid",
+			opts:    auth.JWTOptions{Keys: keys},
+			token:   sign(t, jwt.SigningMethodRS256, "gone", rsaKey),
+			wantErr: true,
+		},
+		{
+			name:    "Algorithm the key is not for",
+			opts:    auth.JWTOptions{Keys: keys},
+			token:   sign(t, jwt.SigningMethodES256, "rsa-only", ecKey),
+			wantErr: true,
+		},
+		{
+			name:    "Algorithm not accepted",
+			opts:    auth.JWTOptions{Keys: keys},
+			token:   sign(t, jwt.SigningMethodRS512, "rsa", rsaKey),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := auth.NewJWTVerifier(tc.opts)
+			require.NoError(t, err)
+
+			token, err := v.Verify(context.Background(), tc.token)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.EqualValues(t, 42, token.UserID)
+		})
+	}
+}
+
+func TestJWTVerifier_Claims(t *testing.T) {
+	secret := []byte("dev-secret")
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": "https://sso.example",
+			"aud": []string{"url-shortener", "other"},
+			"uid": "42",
+			"exp": now.Add(time.Minute).Unix(),
+			"iat": now.Unix(),
+		}
+	}
+
+	cases := []struct {
+		name       string
+		change     func(jwt.MapClaims)
+		wantReason string
+	}{
+		{name: "Valid", change: func(jwt.MapClaims) {}},
+		{
+			name:   "Expired within leeway",
+			change: func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() },
+		},
+		{
+			name:       "Expired",
+			change:     func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() },
+			wantReason: "expired",
+		},
+		{
+			name:       "No expiry",
+			change:     func(c jwt.MapClaims) { delete(c, "exp") },
+			wantReason: "missing required claim",
+		},
+		{
+			name:       "Not valid yet",
+			change:     func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() },
+			wantReason: "not valid yet",
+		},
+		{
+			name:       "Issued in the future",
+			change:     func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() },
+			wantReason: "issued in the future",
+		},
+		{
+			name:       "Other issuer",
+			change:     func(c jwt.MapClaims) { c["iss"] = "https://evil.example" },
+			wantReason: "wrong issuer",
+		},
+		{
+			name:       "No issuer",
+			change:     func(c jwt.MapClaims) { delete(c, "iss") },
+			wantReason: "missing required claim",
+		},
+		{
+			name:       "Other audience",
+			change:     func(c jwt.MapClaims) { c["aud"] = "billing" },
+			wantReason: "wrong audience",
+		},
+		{
+			name: "User ID in a guessed claim",
+			change: func(c jwt.MapClaims) {
+				delete(c, "uid")
+				c["user_id"] = 42
+			},
+			wantReason: "invalid user id",
+		},
+		{
+			name:       "Non-numeric user ID",
+			change:     func(c jwt.MapClaims) { c["uid"] = "alice" },
+			wantReason: "invalid user id",
+		},
+	}
+
+	v, err := auth.NewJWTVerifier(auth.JWTOptions{
+		HMACSecret:  secret,
+		Issuer:      "https://sso.example",
+		Audience:    "url-shortener",
+		Leeway:      30 * time.Second,
+		UserIDClaim: "uid",
+	})
+	require.NoError(t, err)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := valid()
+			tc.change(claims)
+			signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+			require.NoError(t, err)
+
+			token, err := v.Verify(context.Background(), signed)
+			if tc.wantReason != "" {
+				require.Error(t, err)
+				assert.Equal(t, tc.wantReason, auth.RejectionReason(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.EqualValues(t, 42, token.UserID)
+		})
+	}
+}
+
+func TestRejectionReason_KeyAndSignature(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	v, err := auth.NewJWTVerifier(auth.JWTOptions{
+		HMACSecret: []byte("dev-secret"),
+		Keys:       keySet{"rsa": {ID: "rsa", Public: rsaKey.Public()}},
+	})
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, "gone", rsaKey))
+	assert.Equal(t, "unknown signing key", auth.RejectionReason(err))
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, "", []byte("other-secret")))
+	assert.Equal(t, "invalid signature", auth.RejectionReason(err))
+
+	_, err = v.Verify(context.Background(), "not.a.jwt")
+	assert.Equal(t, "malformed", auth.RejectionReason(err))
+}
+
+func TestNewJWTVerifier_NothingConfigured(t *testing.T) {
+	_, err := auth.NewJWTVerifier(auth.JWTOptions{})
+	require.Error(t, err)
+}
+
+func TestJWTVerifier_HMACKeyRotation(t *testing.T) {
+	set, err := hmackeys.NewSet("2026-10", map[string]string{
+		"2026-10": "new-secret",
+		"2026-04": "old-secret",
+	})
+	require.NoError(t, err)
+	v, err := auth.NewJWTVerifier(auth.JWTOptions{HMACKeys: set})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		token      string
+		wantKeyID  string
+		wantReason string
+	}{
+		{
+			name:      "Active key",
+			token:     sign(t, jwt.SigningMethodHS256, "2026-10", []byte("new-secret")),
+			wantKeyID: "2026-10",
+		},
+		{
+			name:      "Verify-only key",
+			token:     sign(t, jwt.SigningMethodHS256, "2026-04", []byte("old-secret")),
+			wantKeyID: "2026-04",
+		},
+		{
+			name:      "No kid uses the active key",
+			token:     sign(t, jwt.SigningMethodHS256, "", []byte("new-secret")),
+			wantKeyID: "2026-10",
+		},
+		{
+			name:       "No kid signed with an old key",
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte("old-secret")),
+			wantReason: "invalid signature",
+		},
+		{
+			name:       "Removed key",
+			token:      sign(t, jwt.SigningMethodHS256, "2025-10", []byte("older-secret")),
+			wantReason: "unknown signing key",
+		},
+		{
+			name:       "Kid of another key",
+			token:      sign(t, jwt.SigningMethodHS256, "2026-10", []byte("old-secret")),
+			wantReason: "invalid signature",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := v.Verify(context.Background(), tc.token)
+			if tc.wantReason != "" {
+				require.Error(t, err)
+				assert.Equal(t, tc.wantReason, auth.RejectionReason(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantKeyID, token.KeyID)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+var ErrTokenRevoked = errors.New("token is revoked")
+
+// RevocationChecker rejects verified tokens that were revoked.
+type RevocationChecker interface {
+	Check(token Token) error
+}
+
+// RevocationStore persists revoked tokens and cutoffs.
+type RevocationStore interface {
+	RevokeToken(t storage.RevokedToken) error
+	RevokedTokens(now time.Time) ([]storage.RevokedToken, error)
+	DeleteExpiredRevokedTokens(now time.Time) (int64, error)
+	SetTokenCutoff(c storage.TokenCutoff) error
+	TokenCutoffs() ([]storage.TokenCutoff, error)
+}
+
+type revocationSet struct {
+	jtis    map[string]struct{}
+	cutoffs map[int64]time.Time
+}
+
+// Revocations keeps the revoked tokens and cutoffs of a RevocationStore
+// in memory, so that checking a token does not query it. Changes made
+// through Revocations apply at once; those made by other instances once
+// Refresh picks them up.
+type Revocations struct {
+	store  RevocationStore
+	leeway time.Duration
+	now    func() time.Time
+
+	set atomic.Pointer[revocationSet]
+
+	// mu serializes refreshes and changes, so that a refresh does not
+	// drop a change made while it was loading.
+	mu sync.Mutex
+}
+
+// NewRevocations loads the revocations, failing if they cannot be.
+// leeway must be the JWTOptions.Leeway of the verifier: revoked tokens
+// are kept that long past their expiry, while it still accepts them.
+func NewRevocations(store RevocationStore, leeway time.Duration) (*Revocations, error) {
+	r := &Revocations{store: store, leeway: leeway, now: time.Now}
+	if err := r.Refresh(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Check returns ErrTokenRevoked if the jti claim of the token was
+// revoked, or its user has a cutoff and the token was not issued after
+// it. Tokens without an iat claim are rejected by any cutoff of their
+// user.
+func (r *Revocations) Check(token Token) error {
+	set := r.set.Load()
+
+	if jti, _ := token.Claims["jti"].(string); jti != "" {
+		if _, ok := set.jtis[jti]; ok {
+			return fmt.Errorf("%w: jti %q", ErrTokenRevoked, jti)
+		}
+	}
+
+	if cutoff, ok := set.cutoffs[token.UserID]; ok {
+		iat, err := token.Claims.GetIssuedAt()
+		if err != nil || iat == nil || iat.Before(cutoff) {
+			return fmt.Errorf("%w: issued before %s", ErrTokenRevoked, cutoff.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+// Refresh reloads the revocations from the store and drops the revoked
+// tokens that expired more than the leeway ago.
+func (r *Revocations) Refresh() error {
+	const op = "auth.Revocations.Refresh"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := r.now().Add(-r.leeway)
+	if _, err := r.store.DeleteExpiredRevokedTokens(expired); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	tokens, err := r.store.RevokedTokens(expired)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	cutoffs, err := r.store.TokenCutoffs()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	set := &revocationSet{
+		jtis:    make(map[string]struct{}, len(tokens)),
+		cutoffs: make(map[int64]time.Time, len(cutoffs)),
+	}
+	for _, t := range tokens {
+		set.jtis[t.JTI] = struct{}{}
+	}
+	for _, c := range cutoffs {
+		set.cutoffs[c.UserID] = c.IssuedBefore
+	}
+	r.set.Store(set)
+
+	return nil
+}
+
+// RevokeToken stores t and rejects its token from now on.
+func (r *Revocations) RevokeToken(t storage.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.store.RevokeToken(t); err != nil {
+		return err
+	}
+
+	old := r.set.Load()
+	set := &revocationSet{jtis: maps.Clone(old.jtis), cutoffs: old.cutoffs}
+	set.jtis[t.JTI] = struct{}{}
+	r.set.Store(set)
+
+	return nil
+}
+
+// SetTokenCutoff stores c and applies it from now on.
+func (r *Revocations) SetTokenCutoff(c storage.TokenCutoff) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.store.SetTokenCutoff(c); err != nil {
+		return err
+	}
+
+	old := r.set.Load()
+	set := &revocationSet{jtis: old.jtis, cutoffs: maps.Clone(old.cutoffs)}
+	set.cutoffs[c.UserID] = c.IssuedBefore
+	r.set.Store(set)
+
+	return nil
+}
+
+// RevokedTokens lists the revoked tokens still in effect at now from the
+// store, which may include changes not yet refreshed.
+func (r *Revocations) RevokedTokens(now time.Time) ([]storage.RevokedToken, error) {
+	return r.store.RevokedTokens(now.Add(-r.leeway))
+}
+
+// TokenCutoffs lists the cutoffs from the store.
+func (r *Revocations) TokenCutoffs() ([]storage.TokenCutoff, error) {
+	return r.store.TokenCutoffs()
+}
+
+// Watch refreshes the revocations every interval until ctx is done.
+// Failures are logged and the previous revocations stay in effect.
+func (r *Revocations) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	log = log.With(slog.String("component", "revocations"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(); err != nil {
+				log.Error("failed to refresh token revocations", sl.Err(err))
+			}
+		}
+	}
+}
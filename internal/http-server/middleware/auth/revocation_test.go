@@ -0,0 +1,182 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/storage"
+)
+
+// revocationStore is shared by instances, like a database.
+type revocationStore struct {
+	tokens  map[string]storage.RevokedToken
+	cutoffs map[int64]storage.TokenCutoff
+}
+
+func newRevocationStore() *revocationStore {
+	return &revocationStore{
+		tokens:  make(map[string]storage.RevokedToken),
+		cutoffs: make(map[int64]storage.TokenCutoff),
+	}
+}
+
+func (s *revocationStore) RevokeToken(t storage.RevokedToken) error {
+	s.tokens[t.JTI] = t
+	return nil
+}
+
+func (s *revocationStore) RevokedTokens(now time.Time) ([]storage.RevokedToken, error) {
+	var tokens []storage.RevokedToken
+	for _, t := range s.tokens {
+		if t.ExpiresAt.IsZero() || t.ExpiresAt.After(now) {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *revocationStore) DeleteExpiredRevokedTokens(now time.Time) (int64, error) {
+	var n int64
+	for jti, t := range s.tokens {
+		if !t.ExpiresAt.IsZero() && !t.ExpiresAt.After(now) {
+			delete(s.tokens, jti)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *revocationStore) SetTokenCutoff(c storage.TokenCutoff) error {
+	s.cutoffs[c.UserID] = c
+	return nil
+}
+
+func (s *revocationStore) TokenCutoffs() ([]storage.TokenCutoff, error) {
+	var cutoffs []storage.TokenCutoff
+	for _, c := range s.cutoffs {
+		cutoffs = append(cutoffs, c)
+	}
+	return cutoffs, nil
+}
+
+func TestRevocations_Check(t *testing.T) {
+	cutoff := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	store := newRevocationStore()
+	require.NoError(t, store.RevokeToken(storage.RevokedToken{JTI: "leaked"}))
+	require.NoError(t, store.SetTokenCutoff(storage.TokenCutoff{UserID: 7, IssuedBefore: cutoff}))
+
+	r, err := auth.NewRevocations(store, 0)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		userID      int64
+		claims      jwt.MapClaims
+		wantRevoked bool
+	}{
+		{name: "Revoked jti", userID: 1, claims: jwt.MapClaims{"jti": "leaked"}, wantRevoked: true},
+		{name: "Other jti", userID: 1, claims: jwt.MapClaims{"jti": "fine"}},
+		{name: "No jti", userID: 1, claims: jwt.MapClaims{}},
+		{name: "Issued before cutoff", userID: 7, claims: jwt.MapClaims{"iat": float64(cutoff.Add(-time.Second).Unix())}, wantRevoked: true},
+		{name: "Issued at cutoff", userID: 7, claims: jwt.MapClaims{"iat": float64(cutoff.Unix())}},
+		{name: "Issued after cutoff", userID: 7, claims: jwt.MapClaims{"iat": float64(cutoff.Add(time.Hour).Unix())}},
+		{name: "No iat with cutoff", userID: 7, claims: jwt.MapClaims{}, wantRevoked: true},
+		{name: "Cutoff of another user", userID: 8, claims: jwt.MapClaims{"iat": float64(cutoff.Add(-time.Hour).Unix())}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.Check(auth.Token{UserID: tc.userID, Claims: tc.claims})
+			if tc.wantRevoked {
+				require.ErrorIs(t, err, auth.ErrTokenRevoked)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRevocations_ChangesAndRefresh(t *testing.T) {
+	store := newRevocationStore()
+	a, err := auth.NewRevocations(store, 0)
+	require.NoError(t, err)
+	b, err := auth.NewRevocations(store, 0)
+	require.NoError(t, err)
+
+	token := auth.Token{UserID: 7, Claims: jwt.MapClaims{"jti": "abc", "iat": float64(time.Now().Add(-time.Minute).Unix())}}
+
+	// A change applies at once on the instance it was made on, and on
+	// others once they refresh.
+	require.NoError(t, a.RevokeToken(storage.RevokedToken{JTI: "abc", ExpiresAt: time.Now().Add(time.Hour)}))
+	require.ErrorIs(t, a.Check(token), auth.ErrTokenRevoked)
+	require.NoError(t, b.Check(token))
+	require.NoError(t, b.Refresh())
+	require.ErrorIs(t, b.Check(token), auth.ErrTokenRevoked)
+
+	other := auth.Token{UserID: 7, Claims: jwt.MapClaims{"jti": "def", "iat": float64(time.Now().Add(-time.Minute).Unix())}}
+	require.NoError(t, b.SetTokenCutoff(storage.TokenCutoff{UserID: 7, IssuedBefore: time.Now()}))
+	require.ErrorIs(t, b.Check(other), auth.ErrTokenRevoked)
+	require.NoError(t, a.Refresh())
+	require.ErrorIs(t, a.Check(other), auth.ErrTokenRevoked)
+}
+
+func TestJWTVerifier_Revoked(t *testing.T) {
+	store := newRevocationStore()
+	require.NoError(t, store.RevokeToken(storage.RevokedToken{JTI: "leaked"}))
+	revocations, err := auth.NewRevocations(store, 0)
+	require.NoError(t, err)
+
+	secret := []byte("dev-secret")
+	v, err := auth.NewJWTVerifier(auth.JWTOptions{HMACSecret: secret, Revocations: revocations})
+	require.NoError(t, err)
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 42,
+		"jti":     "leaked",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), signed)
+	require.Error(t, err)
+	assert.Equal(t, "revoked", auth.RejectionReason(err))
+}
+
+func TestJWTVerifier_RevokedWithinLeeway(t *testing.T) {
+	const leeway = 30 * time.Second
+	exp := time.Now().Add(-10 * time.Second).Truncate(time.Second)
+
+	// The token expired, but the verifier still accepts it for the leeway,
+	// so its revocation must not be dropped yet.
+	store := newRevocationStore()
+	require.NoError(t, store.RevokeToken(storage.RevokedToken{JTI: "leaked", ExpiresAt: exp}))
+	revocations, err := auth.NewRevocations(store, leeway)
+	require.NoError(t, err)
+	assert.Contains(t, store.tokens, "leaked")
+
+	listed, err := revocations.RevokedTokens(time.Now())
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	secret := []byte("dev-secret")
+	v, err := auth.NewJWTVerifier(auth.JWTOptions{HMACSecret: secret, Leeway: leeway, Revocations: revocations})
+	require.NoError(t, err)
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 42,
+		"jti":     "leaked",
+		"exp":     exp.Unix(),
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), signed)
+	require.Error(t, err)
+	assert.Equal(t, "revoked", auth.RejectionReason(err))
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+}
+
+// Role is a set of permissions.
+type Role string
+
+const (
+	// RoleViewer reads links.
+	RoleViewer Role = "viewer"
+	// RoleCreator also creates links and manages the ones they own.
+	RoleCreator Role = "creator"
+	// RoleAdmin may do everything, including managing API keys.
+	RoleAdmin Role = "admin"
+)
+
+// Permission is something a route requires.
+type Permission string
+
+const (
+	PermReadLinks   Permission = "links:read"
+	PermCreateLinks Permission = "links:create"
+	// PermManageOwnLinks allows changing and deleting links one owns.
+	PermManageOwnLinks Permission = "links:manage_own"
+	// PermManageAllLinks allows changing and deleting any link.
+	PermManageAllLinks Permission = "links:manage_all"
+	PermManageKeys     Permission = "keys:manage"
+	// PermRevokeTokens allows revoking the JWTs of any user.
+	PermRevokeTokens Permission = "tokens:revoke"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:  {PermReadLinks},
+	RoleCreator: {PermReadLinks, PermCreateLinks, PermManageOwnLinks},
+	RoleAdmin: {
+		PermReadLinks, PermCreateLinks, PermManageOwnLinks, PermManageAllLinks, PermManageKeys,
+		PermRevokeTokens,
+	},
+}
+
+var ErrUnknownRole = errors.New("unknown role")
+
+// ParseRole accepts the names of the roles above.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := rolePermissions[r]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownRole, s)
+	}
+	return r, nil
+}
+
+// Can reports whether the role has perm.
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleResolver finds the role of an authenticated user.
+type RoleResolver interface {
+	Role(ctx context.Context, userID int64) (Role, error)
+}
+
+// Roles gives users their configured role and asks the AdminChecker only
+// about the others, who are admins if it approves and get the default
+// role otherwise. Configured users therefore keep working while the
+// AdminChecker is unavailable, and their role also wins over it.
+type Roles struct {
+	adminChecker AdminChecker
+	defaultRole  Role
+	users        map[int64]Role
+}
+
+func NewRoles(adminChecker AdminChecker, defaultRole Role, users map[int64]Role) *Roles {
+	return &Roles{
+		adminChecker: adminChecker,
+		defaultRole:  defaultRole,
+		users:        users,
+	}
+}
+
+func (r *Roles) Role(ctx context.Context, userID int64) (Role, error) {
+	if role, ok := r.users[userID]; ok {
+		return role, nil
+	}
+
+	isAdmin, err := r.adminChecker.IsAdmin(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("check admin status: %w", err)
+	}
+	if isAdmin {
+		return RoleAdmin, nil
+	}
+	return r.defaultRole, nil
+}
+
+// Require rejects principals whose role lacks perm. It must run after
+// Authenticate.
+func Require(perm Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok || !p.Role.Can(perm) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LinkGetter loads the link a route acts on.
+type LinkGetter interface {
+	GetURL(domain string, alias string) (storage.URL, error)
+}
+
+// CanManage reports whether p may change or delete a link owned by
+// ownerID. Links without an owner, created before owners were tracked,
+// are managed by admins only.
+func CanManage(p Principal, ownerID int64) bool {
+	if p.Role.Can(PermManageAllLinks) {
+		return true
+	}
+	return ownerID != 0 && ownerID == p.UserID && p.Role.Can(PermManageOwnLinks)
+}
+
+// OwnerOnly rejects principals that may not manage the link in the
+// {alias} path parameter and ?domain= query parameter. Unknown links and
+// domains are left for the handler to report.
+func OwnerOnly(log *slog.Logger, links LinkGetter, allowedDomains *domains.Set) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.OwnerOnly"
+
+			p, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+			if p.Role.Can(PermManageAllLinks) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			domain := allowedDomains.Default()
+			if d := r.URL.Query().Get("domain"); d != "" {
+				if !allowedDomains.IsAllowed(d) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				domain = domains.Normalize(d)
+			}
+
+			u, err := links.GetURL(domain, chi.URLParam(r, "alias"))
+			if err != nil {
+				if errors.Is(err, storage.ErrURLNotFound) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				log.Error("failed to get url", sl.Err(err), slog.String("op", op))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+				return
+			}
+
+			if !CanManage(p, u.OwnerID) {
+				log.Info("link is owned by another user",
+					slog.String("op", op),
+					slog.Int64("user_id", p.UserID),
+					slog.Int64("owner_id", u.OwnerID),
+					slog.String("alias", u.Alias),
+				)
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
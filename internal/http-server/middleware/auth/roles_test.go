@@ -0,0 +1,178 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+type admins map[int64]bool
+
+func (a admins) IsAdmin(_ context.Context, userID int64) (bool, error) {
+	if userID < 0 {
+		return false, errors.New("sso unavailable")
+	}
+	return a[userID], nil
+}
+
+func TestRoles(t *testing.T) {
+	roles := auth.NewRoles(admins{1: true, 4: true}, auth.RoleCreator, map[int64]auth.Role{
+		-2: auth.RoleCreator,
+		1:  auth.RoleViewer,
+		3:  auth.RoleViewer,
+	})
+
+	cases := []struct {
+		name    string
+		userID  int64
+		want    auth.Role
+		wantErr bool
+	}{
+		{name: "Override wins over SSO admin", userID: 1, want: auth.RoleViewer},
+		{name: "Default", userID: 2, want: auth.RoleCreator},
+		{name: "Override", userID: 3, want: auth.RoleViewer},
+		{name: "SSO admin", userID: 4, want: auth.RoleAdmin},
+		{name: "SSO error", userID: -1, wantErr: true},
+		{name: "Override during SSO outage", userID: -2, want: auth.RoleCreator},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			role, err := roles.Role(context.Background(), tc.userID)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, role)
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := auth.ParseRole("creator")
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleCreator, role)
+
+	_, err = auth.ParseRole("owner")
+	require.ErrorIs(t, err, auth.ErrUnknownRole)
+}
+
+type links map[string]storage.URL
+
+func (l links) GetURL(domain string, alias string) (storage.URL, error) {
+	u, ok := l[domain+"/"+alias]
+	if !ok {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+	return u, nil
+}
+
+func TestOwnerOnly(t *testing.T) {
+	store := links{
+		"go.example/mine":   {Alias: "mine", OwnerID: 2},
+		"s.example/mine":    {Alias: "mine", OwnerID: 5},
+		"go.example/legacy": {Alias: "legacy"},
+	}
+
+	cases := []struct {
+		name      string
+		principal *auth.Principal
+		path      string
+		want      int
+	}{
+		{
+			name:      "Owner",
+			principal: &auth.Principal{UserID: 2, Role: auth.RoleCreator},
+			path:      "/url/mine",
+			want:      http.StatusNoContent,
+		},
+		{
+			name:      "Other domain",
+			principal: &auth.Principal{UserID: 2, Role: auth.RoleCreator},
+			path:      "/url/mine?domain=s.example",
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "Link without owner",
+			principal: &auth.Principal{UserID: 2, Role: auth.RoleCreator},
+			path:      "/url/legacy",
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "Viewer owning the link",
+			principal: &auth.Principal{UserID: 2, Role: auth.RoleViewer},
+			path:      "/url/mine",
+			want:      http.StatusForbidden,
+		},
+		{
+			name:      "Admin",
+			principal: &auth.Principal{UserID: 1, Role: auth.RoleAdmin},
+			path:      "/url/legacy",
+			want:      http.StatusNoContent,
+		},
+		{
+			name:      "Unknown link is left to the handler",
+			principal: &auth.Principal{UserID: 2, Role: auth.RoleCreator},
+			path:      "/url/missing",
+			want:      http.StatusNoContent,
+		},
+		{
+			name: "Unauthenticated",
+			path: "/url/mine",
+			want: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.With(auth.OwnerOnly(
+				slogdiscard.NewDiscardLogger(),
+				store,
+				domains.New("go.example", []string{"s.example"}),
+			)).Delete("/url/{alias}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+			if tc.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), *tc.principal))
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.want, rr.Code)
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	h := auth.Require(auth.PermCreateLinks)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for role, want := range map[auth.Role]int{
+		auth.RoleViewer:  http.StatusForbidden,
+		auth.RoleCreator: http.StatusNoContent,
+		auth.RoleAdmin:   http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{UserID: 2, Role: role}))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		assert.Equal(t, want, rr.Code, role)
+	}
+}
@@ -0,0 +1,209 @@
+// Package ratelimit limits requests per client with token buckets.
+//
+// Responses carry the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers of the IETF RateLimit header fields draft.
+// Rejected requests get 429 with Retry-After.
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+)
+
+const (
+	// sweepThreshold is the number of tracked keys from which idle ones
+	// are dropped, at most once per sweepInterval.
+	sweepThreshold = 1024
+	sweepInterval  = time.Second
+)
+
+// Limit allows bursts of Requests, refilled evenly over Per. A zero
+// Limit disables limiting.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// KeyFunc names the bucket a request is counted against.
+type KeyFunc func(r *http.Request) string
+
+// Limiter holds one token bucket per key.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     Limit
+	rate      float64 // tokens per second
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Result describes the bucket of a key after a request.
+type Result struct {
+	Limit     int
+	Remaining int
+	// Reset is when the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is when the next request is allowed; zero if it is now.
+	RetryAfter time.Duration
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		rate:    float64(limit.Requests) / limit.Per.Seconds(),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key and reports whether there
+// was one.
+func (l *Limiter) Allow(key string) (Result, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= sweepThreshold && now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	capacity := float64(l.limit.Requests)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	res := Result{
+		Limit:     l.limit.Requests,
+		Remaining: int(b.tokens),
+		Reset:     l.wait(capacity - b.tokens),
+	}
+	if !allowed {
+		res.RetryAfter = l.wait(1 - b.tokens)
+	}
+
+	return res, allowed
+}
+
+// Len returns the number of tracked keys.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+func (l *Limiter) wait(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+// They are indistinguishable from new ones, so no client gains anything.
+func (l *Limiter) sweep(now time.Time) {
+	l.lastSweep = now
+	capacity := float64(l.limit.Requests)
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// New limits requests per key. A zero limit returns a middleware that
+// passes everything through.
+func New(log *slog.Logger, name string, limit Limit, key KeyFunc) func(next http.Handler) http.Handler {
+	if !limit.enabled() {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	limiter := NewLimiter(limit)
+	log = log.With(slog.String("component", "middleware/ratelimit"), slog.String("group", name))
+	log.Info("rate limiter enabled", slog.Int("requests", limit.Requests), slog.Duration("per", limit.Per))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			res, ok := limiter.Allow(k)
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+			if !ok {
+				log.Info("rate limit exceeded",
+					slog.String("key", k),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+				h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("too many requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds up, so that clients waiting that long are allowed.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// KeyByUserOrIP counts authenticated requests per user and all others
+// per client IP. It must run after auth.Authenticate to see the user.
+func KeyByUserOrIP(r *http.Request) string {
+	if id, ok := auth.UserIDFromContext(r.Context()); ok {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	return KeyByIP(r)
+}
+
+// KeyByIP counts requests per client IP. IPv6 clients usually get a
+// whole /64, so it is counted as one client.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "ip:" + host
+	}
+	if ip.To4() == nil {
+		ip = ip.Mask(net.CIDRMask(64, 128))
+	}
+	return "ip:" + ip.String()
+}
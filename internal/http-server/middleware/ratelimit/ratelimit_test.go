@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewLimiter(Limit{Requests: 3, Per: 3 * time.Second})
+	l.now = func() time.Time { return now }
+
+	for i := 2; i >= 0; i-- {
+		res, ok := l.Allow("a")
+		require.True(t, ok)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, ok := l.Allow("a")
+	require.False(t, ok)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// Other keys have their own bucket.
+	_, ok = l.Allow("b")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	res, ok = l.Allow("a")
+	require.True(t, ok)
+	assert.Equal(t, 0, res.Remaining)
+
+	// Refills never exceed the burst size.
+	now = now.Add(time.Hour)
+	res, ok = l.Allow("a")
+	require.True(t, ok)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestLimiter_EvictsIdleKeys(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewLimiter(Limit{Requests: 1, Per: time.Minute})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < sweepThreshold; i++ {
+		l.Allow(fmt.Sprint(i))
+	}
+	require.Equal(t, sweepThreshold, l.Len())
+
+	// Buckets that are not full yet are kept.
+	now = now.Add(30 * time.Second)
+	l.Allow("new")
+	assert.Equal(t, sweepThreshold+1, l.Len())
+
+	now = now.Add(time.Minute)
+	l.Allow("newer")
+	assert.Equal(t, 1, l.Len())
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := New(slogdiscard.NewDiscardLogger(), "test", Limit{Requests: 2, Per: time.Minute}, KeyByIP)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+
+	do := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("10.0.0.1:1234")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rr.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusNoContent, do("10.0.0.1:5678").Code)
+
+	rr = do("10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, rr.Body.String(), "too many requests")
+
+	assert.Equal(t, http.StatusNoContent, do("10.0.0.2:1234").Code)
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := New(slogdiscard.NewDiscardLogger(), "test", Limit{}, KeyByIP)(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	req.RemoteAddr = "192.0.2.1:1234"
+	assert.Equal(t, "ip:192.0.2.1", KeyByUserOrIP(req))
+
+	req.RemoteAddr = "[2001:db8:1:2:3:4:5:6]:1234"
+	assert.Equal(t, "ip:2001:db8:1:2::", KeyByIP(req))
+
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{UserID: 42}))
+	assert.Equal(t, "user:42", KeyByUserOrIP(req))
+}
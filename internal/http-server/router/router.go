@@ -2,30 +2,92 @@ package router
 
 import (
 	"log/slog"
+	"net/url"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"url-shortener/internal/http-server/handlers/apikeys"
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/tokens"
+	"url-shortener/internal/http-server/handlers/url/broken"
 	"url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/details"
+	"url-shortener/internal/http-server/handlers/url/export"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/preview"
+	"url-shortener/internal/http-server/handlers/url/qr"
 	"url-shortener/internal/http-server/handlers/url/redirect"
+	"url-shortener/internal/http-server/handlers/url/rules"
 	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/schedule"
+	"url-shortener/internal/http-server/handlers/url/search"
+	"url-shortener/internal/http-server/handlers/url/split"
+	"url-shortener/internal/http-server/handlers/url/tags"
 	"url-shortener/internal/http-server/middleware/auth"
 	mwlogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/domains"
+	"url-shortener/internal/lib/urlpolicy"
 )
 
 type Storage interface {
 	save.URLSaver
 	redirect.URLGetter
+	redirect.ClickRecorder
+	preview.ClickCounter
 	delete.URLDeleter
+	broken.BrokenURLLister
+	details.URLGetter
+	rules.RulesSetter
+	split.SplitSetter
+	schedule.ScheduleSetter
+	details.VariantCounter
+	tags.TagAdder
+	tags.TagRemover
+	tags.TagLister
+	list.URLLister
+	search.URLSearcher
+	auth.APIKeyStore
+	auth.LinkGetter
+	apikeys.APIKeySaver
+	apikeys.APIKeyLister
+	apikeys.APIKeyRevoker
+}
+
+// Revocations revoke JWTs, see auth.Revocations.
+type Revocations interface {
+	tokens.TokenRevoker
+	tokens.CutoffSetter
+	tokens.RevocationLister
+}
+
+// RateLimits are the limits per route group; zero limits disable them.
+type RateLimits struct {
+	// Create applies to POST /url on top of API.
+	Create   ratelimit.Limit
+	Redirect ratelimit.Limit
+	API      ratelimit.Limit
+	// Auth applies to /url per client IP before authentication.
+	Auth ratelimit.Limit
 }
 
 func New(
 	log *slog.Logger,
 	storage Storage,
-	adminChecker auth.AdminChecker,
-	appSecret string,
+	roles auth.RoleResolver,
+	allowedDomains *domains.Set,
+	publicBaseURL *url.URL,
+	passwords *redirect.PasswordGate,
+	policy urlpolicy.Policy,
+	fetcher save.MetadataFetcher,
+	tokenVerifier auth.TokenVerifier,
+	revocations Revocations,
 	ssoTimeout time.Duration,
+	rateLimits RateLimits,
+	healthChecks map[string]health.Check,
 ) chi.Router {
 	r := chi.NewRouter()
 
@@ -36,12 +98,87 @@ func New(
 	r.Use(middleware.URLFormat)
 
 	r.Route("/url", func(r chi.Router) {
-		r.Use(auth.AdminOnly(log, adminChecker, appSecret, ssoTimeout))
-		r.Post("/", save.New(log, storage))
-		r.Delete("/{alias}", delete.New(log, storage))
+		// Before auth, so that requests with bad credentials are limited.
+		r.Use(ratelimit.New(log, "auth", rateLimits.Auth, ratelimit.KeyByIP))
+		r.Use(auth.Authenticate(log, roles, storage, tokenVerifier, ssoTimeout))
+		// After auth, so that users are limited per user, not per IP.
+		r.Use(ratelimit.New(log, "api", rateLimits.API, ratelimit.KeyByUserOrIP))
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(apikey.ScopeLinksWrite))
+
+			createLimit := ratelimit.New(log, "create", rateLimits.Create, ratelimit.KeyByUserOrIP)
+			r.With(auth.Require(auth.PermCreateLinks), createLimit).
+				Post("/", save.New(log, storage, allowedDomains, policy, fetcher))
+
+			r.Group(func(r chi.Router) {
+				r.Use(auth.Require(auth.PermManageOwnLinks))
+				r.Use(auth.OwnerOnly(log, storage, allowedDomains))
+
+				r.Delete("/{alias}", delete.New(log, storage, allowedDomains))
+				r.Put("/{alias}/rules", rules.New(log, storage, allowedDomains, policy))
+				r.Put("/{alias}/split", split.New(log, storage, allowedDomains, policy))
+				r.Put("/{alias}/schedule", schedule.New(log, storage, allowedDomains, policy))
+				r.Post("/{alias}/tags", tags.NewAdd(log, storage, allowedDomains))
+				r.Delete("/{alias}/tags/{tag}", tags.NewRemove(log, storage, allowedDomains))
+			})
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(apikey.ScopeLinksRead))
+			r.Use(auth.Require(auth.PermReadLinks))
+
+			r.Get("/", list.New(log, storage))
+			r.Get("/broken", broken.New(log, storage))
+			r.Get("/tags", tags.NewList(log, storage))
+			r.Get("/export", export.New(log, storage))
+			r.Get("/search", search.New(log, storage))
+			r.Get("/{alias}", details.New(log, storage, storage, allowedDomains))
+			r.Get("/{alias}/qr", qr.New(log, storage, allowedDomains, publicBaseURL))
+		})
+
+		// Top-level /keys and /tokens would break redirects of links with
+		// those aliases; here they would shadow their details, so save
+		// rejects them as aliases.
+		r.Route("/keys", func(r chi.Router) {
+			r.Use(auth.UsersOnly)
+			r.Use(auth.Require(auth.PermManageKeys))
+
+			r.Post("/", apikeys.NewCreate(log, storage))
+			r.Get("/", apikeys.NewList(log, storage))
+			r.Delete("/{id}", apikeys.NewRevoke(log, storage))
+		})
+
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(auth.UsersOnly)
+			r.Use(auth.Require(auth.PermRevokeTokens))
+
+			r.Get("/revoked", tokens.NewList(log, revocations))
+			r.Post("/revoked", tokens.NewRevoke(log, revocations))
+			r.Put("/cutoffs/{user_id}", tokens.NewCutoff(log, revocations))
+		})
 	})
 
-	r.Get("/{alias}", redirect.New(log, storage))
+	// Like /url, this would shadow a link with the alias "healthz", which
+	// save rejects.
+	r.Get("/healthz", health.New(log, healthChecks))
+
+	r.Group(func(r chi.Router) {
+		r.Use(ratelimit.New(log, "redirect", rateLimits.Redirect, ratelimit.KeyByIP))
+
+		// Both handlers must agree on whether a scheduled link is live.
+		clock := time.Now
+
+		previewHandler := preview.New(log, storage, storage, allowedDomains, clock)
+		r.Get("/{alias}+", previewHandler)
+
+		// POST reaches the target of 307/308 links and submits link passwords.
+		redirectHandler := redirect.New(log, storage, storage, allowedDomains, passwords, clock)
+		r.With(preview.OnQuery(previewHandler)).Get("/{alias}", redirectHandler)
+		r.Post("/{alias}", redirectHandler)
+		r.Get("/{alias}/*", redirectHandler)
+		r.Post("/{alias}/*", redirectHandler)
+	})
 
 	return r
 }
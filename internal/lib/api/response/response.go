@@ -40,6 +40,12 @@ func ValidationError(err validator.ValidationErrors) Response {
 			errorMsgs = append(errorMsgs, fmt.Sprintf("поле %s является обязательным", err.Field()))
 		case "url":
 			errorMsgs = append(errorMsgs, fmt.Sprintf("поле %s должно быть валидным URL", err.Field()))
+		case "min":
+			errorMsgs = append(errorMsgs, fmt.Sprintf("поле %s должно быть не короче %s", err.Field(), err.Param()))
+		case "max":
+			errorMsgs = append(errorMsgs, fmt.Sprintf("поле %s должно быть не длиннее %s", err.Field(), err.Param()))
+		case "oneof":
+			errorMsgs = append(errorMsgs, fmt.Sprintf("поле %s должно быть одним из: %s", err.Field(), err.Param()))
 		default:
 			errorMsgs = append(errorMsgs, fmt.Sprintf("поле %s является невалидным", err.Field()))
 		}
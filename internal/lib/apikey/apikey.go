@@ -0,0 +1,88 @@
+// Package apikey generates API keys and defines their scopes.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+)
+
+// Scopes lists every scope a key may be given.
+var Scopes = []string{ScopeLinksRead, ScopeLinksWrite}
+
+// keyPrefix marks the keys of this service, e.g. for secret scanners.
+const keyPrefix = "usk_"
+
+// PrefixLength is how much of a key is kept in plain text.
+const PrefixLength = len(keyPrefix) + 8
+
+var ErrInvalidScope = errors.New("invalid scope")
+
+// Generate returns a new random key, its displayable prefix and the hash
+// to store.
+func Generate() (key string, prefix string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("generate api key: %w", err)
+	}
+
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:PrefixLength], Hash(key), nil
+}
+
+// Hash returns the hex SHA-256 of key. Keys are random and long, so a
+// fast hash is enough and lets keys be looked up by their hash.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateScopes checks that scopes is not empty and only holds known
+// scopes, returning them without duplicates.
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	out := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !known(s) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+
+	return out, nil
+}
+
+// HasScope reports whether scopes include scope. Scopes do not imply
+// each other: a key that only creates links cannot read them.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func known(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
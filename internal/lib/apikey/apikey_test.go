@@ -0,0 +1,42 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, "usk_"))
+	assert.Len(t, key, 4+43)
+	assert.Equal(t, key[:PrefixLength], prefix)
+	assert.Equal(t, Hash(key), hash)
+	assert.NotEqual(t, Hash(key+"x"), hash)
+
+	other, _, _, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestValidateScopes(t *testing.T) {
+	got, err := ValidateScopes([]string{"links:write", "links:read", "links:write"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"links:write", "links:read"}, got)
+
+	_, err = ValidateScopes(nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, err = ValidateScopes([]string{"admin"})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestHasScope(t *testing.T) {
+	assert.False(t, HasScope([]string{ScopeLinksWrite}, ScopeLinksRead))
+	assert.True(t, HasScope([]string{ScopeLinksRead}, ScopeLinksRead))
+	assert.False(t, HasScope([]string{ScopeLinksRead}, ScopeLinksWrite))
+	assert.False(t, HasScope(nil, ScopeLinksRead))
+}
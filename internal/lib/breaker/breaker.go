@@ -0,0 +1,209 @@
+// Package breaker stops calling a failing dependency for a while, so that
+// callers fail fast instead of waiting for timeouts and retries.
+//
+// A closed breaker lets calls through and counts consecutive failures.
+// Reaching the threshold opens it: calls fail with an *OpenError until
+// the open timeout passes. Then it is half-open and lets one trial call
+// through at a time; enough successful trials close it, a failed one
+// opens it again.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// OpenError rejects a call without making it. It matches ErrOpen.
+type OpenError struct {
+	// RetryAfter is when the breaker lets a trial call through.
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return ErrOpen.Error()
+}
+
+func (e *OpenError) Unwrap() error {
+	return ErrOpen
+}
+
+// halfOpenRetryAfter is suggested to calls rejected while a trial call
+// is in flight.
+const halfOpenRetryAfter = time.Second
+
+type Options struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker. Zero disables the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open. Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenSuccesses is the number of successful trial calls that
+	// closes the breaker. Defaults to 1.
+	HalfOpenSuccesses int
+	// IsFailure decides which errors count as failures of the dependency
+	// rather than, say, rejected input. Defaults to every error.
+	IsFailure func(error) bool
+	// OnStateChange is called, with the breaker locked, on every
+	// transition.
+	OnStateChange func(from, to State)
+}
+
+type Breaker struct {
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	openedAt  time.Time
+	trial     bool
+}
+
+func New(opts Options) *Breaker {
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 30 * time.Second
+	}
+	if opts.HalfOpenSuccesses <= 0 {
+		opts.HalfOpenSuccesses = 1
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(error) bool { return true }
+	}
+	return &Breaker{opts: opts, now: time.Now}
+}
+
+// Do calls fn unless the breaker is open and records its outcome.
+func (b *Breaker) Do(fn func() error) error {
+	if b.opts.FailureThreshold <= 0 {
+		return fn()
+	}
+
+	trial, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	b.record(trial, err != nil && b.opts.IsFailure(err))
+	return err
+}
+
+// State is the current state. An open breaker whose timeout passed is
+// still reported open until the next call.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// RetryAfter is how long an open breaker keeps rejecting calls; zero
+// otherwise.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != Open {
+		return 0
+	}
+	return max(b.openedAt.Add(b.opts.OpenTimeout).Sub(b.now()), 0)
+}
+
+func (b *Breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		wait := b.openedAt.Add(b.opts.OpenTimeout).Sub(b.now())
+		if wait > 0 {
+			return false, &OpenError{RetryAfter: wait}
+		}
+		b.setState(HalfOpen)
+		b.trial = true
+		return true, nil
+	case HalfOpen:
+		if b.trial {
+			return false, &OpenError{RetryAfter: halfOpenRetryAfter}
+		}
+		b.trial = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (b *Breaker) record(trial bool, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.open()
+		}
+	case HalfOpen:
+		// Calls let through before the breaker opened may finish now;
+		// only trials decide.
+		if !trial {
+			return
+		}
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenSuccesses {
+			b.failures = 0
+			b.setState(Closed)
+		}
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.successes = 0
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(to State) {
+	from := b.state
+	b.state = to
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
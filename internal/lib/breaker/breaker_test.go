@@ -0,0 +1,100 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errDown     = errors.New("unavailable")
+	errNotFound = errors.New("not found")
+)
+
+func TestBreaker(t *testing.T) {
+	var transitions []string
+	b := New(Options{
+		FailureThreshold:  3,
+		OpenTimeout:       10 * time.Second,
+		HalfOpenSuccesses: 2,
+		IsFailure:         func(err error) bool { return !errors.Is(err, errNotFound) },
+		OnStateChange: func(from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	now := time.Unix(1_700_000_000, 0)
+	b.now = func() time.Time { return now }
+
+	fail := func() error { return errDown }
+	ok := func() error { return nil }
+
+	// Errors that are not failures reset the count.
+	require.ErrorIs(t, b.Do(fail), errDown)
+	require.ErrorIs(t, b.Do(fail), errDown)
+	require.ErrorIs(t, b.Do(func() error { return errNotFound }), errNotFound)
+	require.ErrorIs(t, b.Do(fail), errDown)
+	require.ErrorIs(t, b.Do(fail), errDown)
+	assert.Equal(t, Closed, b.State())
+
+	require.ErrorIs(t, b.Do(fail), errDown)
+	assert.Equal(t, Open, b.State())
+
+	calls := 0
+	err := b.Do(func() error { calls++; return nil })
+	var openErr *OpenError
+	require.ErrorAs(t, err, &openErr)
+	require.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, 10*time.Second, openErr.RetryAfter)
+	assert.Zero(t, calls)
+
+	now = now.Add(4 * time.Second)
+	assert.Equal(t, 6*time.Second, b.RetryAfter())
+
+	// A failed trial opens the breaker again.
+	now = now.Add(6 * time.Second)
+	require.ErrorIs(t, b.Do(fail), errDown)
+	assert.Equal(t, Open, b.State())
+
+	now = now.Add(10 * time.Second)
+	require.NoError(t, b.Do(ok))
+	assert.Equal(t, HalfOpen, b.State())
+	require.NoError(t, b.Do(ok))
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+func TestBreaker_OneTrialAtATime(t *testing.T) {
+	b := New(Options{FailureThreshold: 1, OpenTimeout: time.Second})
+	now := time.Unix(1_700_000_000, 0)
+	b.now = func() time.Time { return now }
+
+	require.Error(t, b.Do(func() error { return errDown }))
+	now = now.Add(time.Second)
+
+	err := b.Do(func() error {
+		// A concurrent call while the trial is in flight.
+		inner := b.Do(func() error { return nil })
+		require.ErrorIs(t, inner, ErrOpen)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := New(Options{})
+	for range 10 {
+		require.ErrorIs(t, b.Do(func() error { return errDown }), errDown)
+	}
+	assert.Equal(t, Closed, b.State())
+}
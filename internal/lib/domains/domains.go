@@ -0,0 +1,62 @@
+package domains
+
+import (
+	"net"
+	"strings"
+)
+
+// Set is the list of short domains served by this deployment.
+// The default domain is always allowed and is used for requests
+// whose Host does not match any configured domain.
+type Set struct {
+	def     string
+	allowed map[string]struct{}
+}
+
+func New(defaultDomain string, allowed []string) *Set {
+	s := &Set{
+		def:     Normalize(defaultDomain),
+		allowed: make(map[string]struct{}, len(allowed)+1),
+	}
+
+	s.allowed[s.def] = struct{}{}
+	for _, d := range allowed {
+		if d = Normalize(d); d != "" {
+			s.allowed[d] = struct{}{}
+		}
+	}
+
+	return s
+}
+
+func (s *Set) Default() string {
+	return s.def
+}
+
+func (s *Set) IsAllowed(domain string) bool {
+	_, ok := s.allowed[Normalize(domain)]
+	return ok
+}
+
+// Resolve maps a request Host to the domain links are stored under.
+// The host is matched as is first and then without the port, so both
+// "go.example" and "go.example:8080" may be configured.
+func (s *Set) Resolve(host string) string {
+	host = Normalize(host)
+	if _, ok := s.allowed[host]; ok {
+		return host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		if _, ok := s.allowed[h]; ok {
+			return h
+		}
+	}
+
+	return s.def
+}
+
+func Normalize(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	return strings.TrimSuffix(domain, ".")
+}
@@ -0,0 +1,57 @@
+package domains
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetResolve(t *testing.T) {
+	s := New("go.example", []string{"S.example.", "local.example:8082"})
+
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{
+			name: "default domain",
+			host: "go.example",
+			want: "go.example",
+		},
+		{
+			name: "allowed domain with port",
+			host: "s.example:8082",
+			want: "s.example",
+		},
+		{
+			name: "allowed domain is normalized",
+			host: "S.EXAMPLE",
+			want: "s.example",
+		},
+		{
+			name: "domain configured with port",
+			host: "local.example:8082",
+			want: "local.example:8082",
+		},
+		{
+			name: "unknown domain falls back to default",
+			host: "127.0.0.1:8082",
+			want: "go.example",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Resolve(tt.host))
+		})
+	}
+}
+
+func TestSetIsAllowed(t *testing.T) {
+	s := New("go.example", []string{"s.example"})
+
+	assert.True(t, s.IsAllowed("go.example"))
+	assert.True(t, s.IsAllowed("S.Example"))
+	assert.False(t, s.IsAllowed("evil.example"))
+	assert.False(t, s.IsAllowed(""))
+}
@@ -0,0 +1,96 @@
+// Package hmackeys holds the shared secrets of HS256 JWTs. Each secret has
+// a key ID, sent in the kid header of the tokens it signs. One key is
+// active and signs new tokens; the others only verify tokens signed
+// before a rotation until they expire.
+package hmackeys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidSet  = errors.New("invalid key set")
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// Set is an immutable set of keys.
+type Set struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewSet makes a set of keys by ID, of which active signs new tokens.
+func NewSet(active string, keys map[string]string) (*Set, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrInvalidSet)
+	}
+
+	s := &Set{active: active, keys: make(map[string][]byte, len(keys))}
+	for id, secret := range keys {
+		if id == "" {
+			return nil, fmt.Errorf("%w: empty key id", ErrInvalidSet)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("%w: key %q is empty", ErrInvalidSet, id)
+		}
+		s.keys[id] = []byte(secret)
+	}
+	if _, ok := s.keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active key %q is not in the set", ErrInvalidSet, active)
+	}
+
+	return s, nil
+}
+
+// document is the format of a secrets file.
+type document struct {
+	Active string            `yaml:"active"`
+	Keys   map[string]string `yaml:"keys"`
+}
+
+// Parse reads a set from YAML or JSON of the form
+//
+//	active: "2026-10"
+//	keys:
+//	  "2026-10": "new secret"
+//	  "2026-04": "previous secret"
+func Parse(data []byte) (*Set, error) {
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSet, err)
+	}
+	return NewSet(doc.Active, doc.Keys)
+}
+
+// Key returns the secret with the given ID and the ID itself. Tokens
+// without a key ID, signed before keys had one, are verified with the
+// active key.
+func (s *Set) Key(kid string) (string, []byte, error) {
+	if kid == "" {
+		kid = s.active
+	}
+	secret, ok := s.keys[kid]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+	}
+	return kid, secret, nil
+}
+
+// Active returns the key that signs new tokens.
+func (s *Set) Active() (string, []byte) {
+	return s.active, s.keys[s.active]
+}
+
+// IDs lists the key IDs, sorted.
+func (s *Set) IDs() []string {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
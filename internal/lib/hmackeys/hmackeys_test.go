@@ -0,0 +1,117 @@
+package hmackeys_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/hmackeys"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name    string
+		doc     string
+		wantErr bool
+	}{
+		{
+			name: "YAML",
+			doc:  "active: \"2026-10\"\nkeys:\n  \"2026-10\": new\n  \"2026-04\": old\n",
+		},
+		{
+			name: "JSON",
+			doc:  `{"active": "2026-10", "keys": {"2026-10": "new", "2026-04": "old"}}`,
+		},
+		{name: "No keys", doc: `active: "2026-10"`, wantErr: true},
+		{name: "Active key missing", doc: "active: \"2027-01\"\nkeys:\n  \"2026-10\": new\n", wantErr: true},
+		{name: "Empty secret", doc: "active: \"2026-10\"\nkeys:\n  \"2026-10\": \"\"\n", wantErr: true},
+		{name: "Not YAML", doc: "{", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			set, err := hmackeys.Parse([]byte(tc.doc))
+			if tc.wantErr {
+				require.ErrorIs(t, err, hmackeys.ErrInvalidSet)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"2026-04", "2026-10"}, set.IDs())
+			id, secret := set.Active()
+			assert.Equal(t, "2026-10", id)
+			assert.Equal(t, []byte("new"), secret)
+
+			id, secret, err = set.Key("2026-04")
+			require.NoError(t, err)
+			assert.Equal(t, "2026-04", id)
+			assert.Equal(t, []byte("old"), secret)
+
+			id, _, err = set.Key("")
+			require.NoError(t, err)
+			assert.Equal(t, "2026-10", id)
+
+			_, _, err = set.Key("2025-10")
+			require.ErrorIs(t, err, hmackeys.ErrKeyNotFound)
+		})
+	}
+}
+
+func TestSource_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("active: a\nkeys:\n  a: first\n"), 0o600))
+
+	s, err := hmackeys.New(hmackeys.Options{File: path})
+	require.NoError(t, err)
+	id, _ := s.Active()
+	assert.Equal(t, "a", id)
+
+	reloaded, err := s.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged file")
+
+	// Rotate: b becomes active, a only verifies.
+	require.NoError(t, os.WriteFile(path, []byte("active: b\nkeys:\n  a: first\n  b: second\n"), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	reloaded, err = s.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	id, secret := s.Active()
+	assert.Equal(t, "b", id)
+	assert.Equal(t, []byte("second"), secret)
+	_, _, err = s.Key("a")
+	require.NoError(t, err)
+
+	// A broken file keeps the current keys.
+	require.NoError(t, os.WriteFile(path, []byte("active: c\n"), 0o600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	_, err = s.Reload()
+	require.Error(t, err)
+	id, _ = s.Active()
+	assert.Equal(t, "b", id)
+}
+
+func TestNew(t *testing.T) {
+	_, err := hmackeys.New(hmackeys.Options{})
+	require.Error(t, err)
+
+	_, err = hmackeys.New(hmackeys.Options{File: filepath.Join(t.TempDir(), "missing.yaml")})
+	require.Error(t, err)
+
+	set, err := hmackeys.NewSet("a", map[string]string{"a": "secret"})
+	require.NoError(t, err)
+	s, err := hmackeys.New(hmackeys.Options{Static: set})
+	require.NoError(t, err)
+
+	reloaded, err := s.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+}
@@ -0,0 +1,131 @@
+package hmackeys
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Options struct {
+	// File is a secrets file in the format of Parse. It is re-read by
+	// Reload when it changes.
+	File string
+	// Static is used when File is empty.
+	Static *Set
+}
+
+// Source serves the current key set, from a file or fixed at start.
+type Source struct {
+	file string
+
+	set atomic.Pointer[Set]
+
+	// mu serializes reloads.
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// New loads the key set, failing if it cannot be.
+func New(opts Options) (*Source, error) {
+	const op = "hmackeys.New"
+
+	if (opts.File == "") == (opts.Static == nil) {
+		return nil, fmt.Errorf("%s: exactly one of file and static keys must be set", op)
+	}
+
+	s := &Source{file: opts.File}
+	if opts.Static != nil {
+		s.set.Store(opts.Static)
+		return s, nil
+	}
+
+	if _, err := s.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return s, nil
+}
+
+// Key looks a key up in the current set, see Set.Key.
+func (s *Source) Key(kid string) (string, []byte, error) {
+	return s.set.Load().Key(kid)
+}
+
+// Active returns the key that currently signs new tokens.
+func (s *Source) Active() (string, []byte) {
+	return s.set.Load().Active()
+}
+
+// Reload re-reads the file if it changed since the last load and
+// reports whether the set was replaced. On error the current set stays
+// in effect. Static sets are never reloaded.
+func (s *Source) Reload() (bool, error) {
+	const op = "hmackeys.Reload"
+
+	if s.file == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if s.set.Load() != nil && info.ModTime().Equal(s.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.set.Store(set)
+	s.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// Watch reloads the file every interval until ctx is done. Failures are
+// logged and the previous keys stay in effect.
+func (s *Source) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if interval <= 0 || s.file == "" {
+		return
+	}
+
+	log = log.With(slog.String("component", "hmackeys"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := s.Reload()
+			if err != nil {
+				log.Error("failed to reload signing keys", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				active, _ := s.Active()
+				log.Info("signing keys reloaded",
+					slog.String("active", active),
+					slog.Any("keys", s.set.Load().IDs()),
+				)
+			}
+		}
+	}
+}
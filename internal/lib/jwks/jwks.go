@@ -0,0 +1,207 @@
+// Package jwks reads JSON Web Key Sets (RFC 7517) with the public keys
+// that verify asymmetrically signed JWTs.
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrInvalidSet  = errors.New("invalid key set")
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// minRSABits rejects keys too weak to trust.
+const minRSABits = 2048
+
+// Key is a public key of a set.
+type Key struct {
+	ID string
+	// Algorithm is the JWS algorithm the key is restricted to, e.g.
+	// RS256; empty when the set does not say.
+	Algorithm string
+	// Public is an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+	Public crypto.PublicKey
+}
+
+// Set is a parsed key set.
+type Set struct {
+	keys map[string]Key
+}
+
+// Key looks a key up by its ID. A token without a key ID matches the
+// only key of a set with a single key.
+func (s *Set) Key(kid string) (Key, bool) {
+	if k, ok := s.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Len is the number of usable keys.
+func (s *Set) Len() int {
+	return len(s.keys)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Parse reads a JWKS document. Encryption keys and key types other than
+// RSA, EC and Ed25519 are skipped, as RFC 7517 asks; malformed keys of
+// the supported types are errors.
+func Parse(data []byte) (*Set, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSet, err)
+	}
+
+	s := &Set{keys: make(map[string]Key, len(doc.Keys))}
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %d (%q): %w", ErrInvalidSet, i+1, k.Kid, err)
+		}
+		if pub == nil {
+			continue
+		}
+
+		if _, ok := s.keys[k.Kid]; ok {
+			return nil, fmt.Errorf("%w: duplicate key id %q", ErrInvalidSet, k.Kid)
+		}
+		s.keys[k.Kid] = Key{ID: k.Kid, Algorithm: k.Alg, Public: pub}
+	}
+
+	return s, nil
+}
+
+// publicKey returns nil for unsupported key types.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		return k.rsaKey()
+	case "EC":
+		return k.ecKey()
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decode("n", k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decode("e", k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n)}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	pub.E = int(exp.Int64())
+
+	if pub.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA key is shorter than %d bits", minRSABits)
+	}
+
+	return pub, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	var (
+		curve elliptic.Curve
+		check ecdh.Curve
+	)
+	switch k.Crv {
+	case "P-256":
+		curve, check = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, check = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, check = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decode("x", k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode("y", k.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, errors.New("invalid EC coordinate size")
+	}
+
+	// ecdh validates that the point is on the curve.
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := check.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("invalid EC point: %w", err)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
+
+func decode(name string, s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%q is missing", name)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", name, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,235 @@
+package jwks_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/jwks"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(t *testing.T, kid string) (map[string]string, *rsa.PublicKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "alg": "RS256",
+		"n": b64(key.N.Bytes()),
+		"e": b64([]byte{1, 0, 1}),
+	}, &key.PublicKey
+}
+
+func ecJWK(t *testing.T, kid string) (map[string]string, *ecdsa.PublicKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(key.X.FillBytes(make([]byte, 32))),
+		"y": b64(key.Y.FillBytes(make([]byte, 32))),
+	}, &key.PublicKey
+}
+
+func edJWK(t *testing.T, kid string) (map[string]string, ed25519.PublicKey) {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(pub)}, pub
+}
+
+func document(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse(t *testing.T) {
+	rsaKey, rsaPub := rsaJWK(t, "rsa")
+	ecKey, ecPub := ecJWK(t, "ec")
+	edKey, edPub := edJWK(t, "ed")
+	encKey, _ := rsaJWK(t, "enc")
+	encKey["use"] = "enc"
+
+	set, err := jwks.Parse(document(t,
+		rsaKey, ecKey, edKey, encKey,
+		map[string]string{"kty": "oct", "kid": "secret", "k": "c2VjcmV0"},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, 3, set.Len())
+
+	k, ok := set.Key("rsa")
+	require.True(t, ok)
+	assert.Equal(t, "RS256", k.Algorithm)
+	assert.True(t, rsaPub.Equal(k.Public))
+
+	k, ok = set.Key("ec")
+	require.True(t, ok)
+	assert.True(t, ecPub.Equal(k.Public))
+
+	k, ok = set.Key("ed")
+	require.True(t, ok)
+	assert.True(t, edPub.Equal(k.Public))
+
+	_, ok = set.Key("enc")
+	assert.False(t, ok)
+	_, ok = set.Key("")
+	assert.False(t, ok, "a missing kid is ambiguous with several keys")
+}
+
+func TestParse_SingleKeyWithoutKid(t *testing.T) {
+	edKey, edPub := edJWK(t, "")
+
+	set, err := jwks.Parse(document(t, edKey))
+	require.NoError(t, err)
+
+	k, ok := set.Key("")
+	require.True(t, ok)
+	assert.True(t, edPub.Equal(k.Public))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	ecKey, _ := ecJWK(t, "ec")
+	offCurve := map[string]string{}
+	for k, v := range ecKey {
+		offCurve[k] = v
+	}
+	offCurve["y"] = b64(make([]byte, 32))
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Not JSON", data: []byte("{")},
+		{name: "Point not on curve", data: document(t, offCurve)},
+		{name: "Short RSA key", data: document(t, map[string]string{
+			"kty": "RSA", "kid": "weak", "n": b64(weak.N.Bytes()), "e": b64([]byte{1, 0, 1}),
+		})},
+		{name: "Missing coordinate", data: document(t, map[string]string{"kty": "OKP", "crv": "Ed25519"})},
+		{name: "Duplicate kid", data: document(t, ecKey, ecKey)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := jwks.Parse(tc.data)
+			require.ErrorIs(t, err, jwks.ErrInvalidSet)
+		})
+	}
+}
+
+func TestSource_File(t *testing.T) {
+	first, _ := edJWK(t, "first")
+	second, _ := edJWK(t, "second")
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, document(t, first), 0o600))
+
+	src, err := jwks.New(context.Background(), jwks.Options{File: path})
+	require.NoError(t, err)
+
+	reloaded, err := src.Reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged file is not re-read")
+
+	require.NoError(t, os.WriteFile(path, document(t, second), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	reloaded, err = src.Reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	_, err = src.Key(context.Background(), "second")
+	require.NoError(t, err)
+}
+
+func TestSource_URLRefreshesOnUnknownKid(t *testing.T) {
+	old, _ := edJWK(t, "old")
+	rotated, _ := edJWK(t, "new")
+
+	var (
+		doc      atomic.Pointer[[]byte]
+		requests atomic.Int32
+	)
+	initial := document(t, old)
+	doc.Store(&initial)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(*doc.Load())
+	}))
+	t.Cleanup(srv.Close)
+
+	src, err := jwks.New(context.Background(), jwks.Options{URL: srv.URL, MinRefreshInterval: time.Nanosecond})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, requests.Load())
+
+	_, err = src.Key(context.Background(), "old")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, requests.Load(), "known keys are served from the cache")
+
+	next := document(t, old, rotated)
+	doc.Store(&next)
+
+	_, err = src.Key(context.Background(), "new")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, requests.Load())
+
+	_, err = src.Key(context.Background(), "forged")
+	require.ErrorIs(t, err, jwks.ErrKeyNotFound)
+}
+
+func TestSource_UnknownKidRefreshIsThrottled(t *testing.T) {
+	key, _ := edJWK(t, "only")
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(document(t, key))
+	}))
+	t.Cleanup(srv.Close)
+
+	src, err := jwks.New(context.Background(), jwks.Options{URL: srv.URL, MinRefreshInterval: time.Hour})
+	require.NoError(t, err)
+
+	for range 5 {
+		_, err = src.Key(context.Background(), "forged")
+		require.ErrorIs(t, err, jwks.ErrKeyNotFound)
+	}
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+func TestNew_Errors(t *testing.T) {
+	_, err := jwks.New(context.Background(), jwks.Options{})
+	require.Error(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err = jwks.New(context.Background(), jwks.Options{URL: srv.URL})
+	require.Error(t, err)
+}
@@ -0,0 +1,225 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// maxDocumentSize bounds the JWKS documents fetched from a URL.
+const maxDocumentSize = 1 << 20
+
+type Options struct {
+	// File or URL of the JWKS document; exactly one must be set.
+	File string
+	URL  string
+	// Client fetches URL; it defaults to a client with Timeout.
+	Client  *http.Client
+	Timeout time.Duration
+	// MinRefreshInterval limits the reloads triggered by tokens with an
+	// unknown key ID, which are expected right after the issuer rotates
+	// its keys but may also be forged. Defaults to 30 seconds.
+	MinRefreshInterval time.Duration
+}
+
+// Source caches a key set loaded from a file or URL. Watch refreshes it
+// periodically; Key also refreshes it when asked for an unknown key.
+type Source struct {
+	file       string
+	url        string
+	client     *http.Client
+	minRefresh time.Duration
+
+	set atomic.Pointer[Set]
+
+	// mu serializes reloads.
+	mu          sync.Mutex
+	modTime     time.Time
+	lastRefresh time.Time
+}
+
+// New loads the key set, failing if it cannot be.
+func New(ctx context.Context, opts Options) (*Source, error) {
+	const op = "jwks.New"
+
+	if (opts.File == "") == (opts.URL == "") {
+		return nil, fmt.Errorf("%s: exactly one of file and url must be set", op)
+	}
+
+	s := &Source{
+		file:       opts.File,
+		url:        opts.URL,
+		client:     opts.Client,
+		minRefresh: opts.MinRefreshInterval,
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: opts.Timeout}
+	}
+	if s.minRefresh <= 0 {
+		s.minRefresh = 30 * time.Second
+	}
+
+	if _, err := s.Reload(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Key returns the key with the given ID, reloading the set once if it is
+// not known.
+func (s *Source) Key(ctx context.Context, kid string) (Key, error) {
+	if k, ok := s.set.Load().Key(kid); ok {
+		return k, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another request may have reloaded the set while this one waited.
+	if k, ok := s.set.Load().Key(kid); ok {
+		return k, nil
+	}
+	if time.Since(s.lastRefresh) >= s.minRefresh {
+		// A failed reload keeps the current set, which lacks the key.
+		_, _ = s.reload(ctx)
+		if k, ok := s.set.Load().Key(kid); ok {
+			return k, nil
+		}
+	}
+
+	return Key{}, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+}
+
+// Reload re-reads the key set; a file is only read when it changed. It
+// reports whether the set was replaced.
+func (s *Source) Reload(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reload(ctx)
+}
+
+// reload is Reload with mu held.
+func (s *Source) reload(ctx context.Context) (bool, error) {
+	const op = "jwks.Reload"
+
+	s.lastRefresh = time.Now()
+
+	var (
+		data    []byte
+		modTime time.Time
+		err     error
+	)
+	if s.file != "" {
+		var changed bool
+		data, modTime, changed, err = s.readFile()
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		if !changed {
+			return false, nil
+		}
+	} else {
+		data, err = s.fetch(ctx)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	set, err := Parse(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if set.Len() == 0 {
+		return false, fmt.Errorf("%s: %w: no usable keys", op, ErrInvalidSet)
+	}
+
+	s.set.Store(set)
+	s.modTime = modTime
+
+	return true, nil
+}
+
+// readFile reads the file unless it is unchanged since the last load.
+func (s *Source) readFile() ([]byte, time.Time, bool, error) {
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	if s.set.Load() != nil && info.ModTime().Equal(s.modTime) {
+		return nil, time.Time{}, false, nil
+	}
+
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return data, info.ModTime(), true, nil
+}
+
+func (s *Source) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDocumentSize {
+		return nil, errors.New("document is too large")
+	}
+
+	return data, nil
+}
+
+// Watch reloads the key set every interval until ctx is done. Failures
+// are logged and the previous keys stay in effect.
+func (s *Source) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	log = log.With(slog.String("component", "jwks"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := s.Reload(ctx)
+			if err != nil {
+				log.Error("failed to reload key set", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("key set reloaded", slog.Int("keys", s.set.Load().Len()))
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var (
+	ErrInvalidColor = errors.New("invalid color")
+	ErrInvalidLevel = errors.New("invalid error correction level")
+)
+
+// Options controls how a QR code is rendered.
+type Options struct {
+	// Size is the edge length of the image in pixels. The code is scaled
+	// by a whole number of pixels per module and centered, so it stays
+	// sharp; Size is raised to the module count if it is smaller.
+	Size int
+	// Margin is the quiet zone around the code, in modules.
+	Margin     int
+	Level      qrcode.RecoveryLevel
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+func PNG(content string, opts Options) ([]byte, error) {
+	const op = "qr.PNG"
+
+	bitmap, err := modules(content, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	size, scale, offset := layout(len(bitmap), opts.Size)
+
+	img := image.NewPaletted(
+		image.Rect(0, 0, size, size),
+		color.Palette{opts.Background, opts.Foreground},
+	)
+	for y, row := range bitmap {
+		for x, set := range row {
+			if !set {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func SVG(content string, opts Options) ([]byte, error) {
+	const op = "qr.SVG"
+
+	bitmap, err := modules(content, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n := len(bitmap)
+	size, _, _ := layout(n, opts.Size)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, n, n,
+	)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, n, n, hex(opts.Background))
+
+	// One path with a horizontal run per sequence of set modules.
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hex(opts.Foreground))
+	for y, row := range bitmap {
+		for x := 0; x < n; {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < n && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// modules returns the code matrix including the quiet zone.
+func modules(content string, opts Options) ([][]bool, error) {
+	q, err := qrcode.New(content, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+
+	code := q.Bitmap()
+	n := len(code) + 2*opts.Margin
+
+	bitmap := make([][]bool, n)
+	for y := range bitmap {
+		bitmap[y] = make([]bool, n)
+	}
+	for y, row := range code {
+		copy(bitmap[y+opts.Margin][opts.Margin:], row)
+	}
+
+	return bitmap, nil
+}
+
+func layout(modules int, size int) (int, int, int) {
+	if size < modules {
+		size = modules
+	}
+	scale := size / modules
+	return size, scale, (size - scale*modules) / 2
+}
+
+// ParseLevel accepts the usual L, M, Q and H letters.
+func ParseLevel(s string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return qrcode.Low, nil
+	case "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	default:
+		return 0, ErrInvalidLevel
+	}
+}
+
+// ParseColor accepts RGB hex colors with an optional leading '#',
+// in either the 6 or the 3 digit form.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
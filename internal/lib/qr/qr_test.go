@@ -0,0 +1,108 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPNG(t *testing.T) {
+	fg := color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+	bg := color.RGBA{R: 0xff, G: 0xee, B: 0xdd, A: 0xff}
+
+	data, err := PNG("https://go.example/docs", Options{
+		Size:       300,
+		Margin:     4,
+		Level:      qrcode.Medium,
+		Foreground: fg,
+		Background: bg,
+	})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	// The corner is inside the quiet zone, the finder pattern starts
+	// right after the margin.
+	assert.Equal(t, bg, color.RGBAModel.Convert(img.At(0, 0)))
+
+	n := len(mustModules(t, "https://go.example/docs", 4))
+	scale := 300 / n
+	offset := (300 - scale*n) / 2
+	assert.Equal(t, fg, color.RGBAModel.Convert(img.At(offset+4*scale, offset+4*scale)))
+}
+
+func TestPNG_SizeBelowModules(t *testing.T) {
+	data, err := PNG("https://go.example/docs", Options{Size: 1, Level: qrcode.Low})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	n := len(mustModules(t, "https://go.example/docs", 0))
+	assert.Equal(t, n, img.Bounds().Dx())
+}
+
+func TestSVG(t *testing.T) {
+	data, err := SVG("https://go.example/docs", Options{
+		Size:       256,
+		Margin:     2,
+		Level:      qrcode.High,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	})
+	require.NoError(t, err)
+
+	var doc struct {
+		XMLName xml.Name `xml:"svg"`
+		Width   string   `xml:"width,attr"`
+		ViewBox string   `xml:"viewBox,attr"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc))
+
+	assert.Equal(t, "256", doc.Width)
+	assert.Contains(t, string(data), `fill="#000000"`)
+	assert.Contains(t, string(data), `fill="#ffffff"`)
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{in: "#ff8000", want: color.RGBA{R: 0xff, G: 0x80, A: 0xff}},
+		{in: "00FF00", want: color.RGBA{G: 0xff, A: 0xff}},
+		{in: "#fff", want: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{in: "red", wantErr: true},
+		{in: "#12345", wantErr: true},
+		{in: "zzzzzz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseColor(tt.in)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidColor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func mustModules(t *testing.T, content string, margin int) [][]bool {
+	t.Helper()
+	m, err := modules(content, Options{Margin: margin, Level: qrcode.Medium})
+	require.NoError(t, err)
+	return m
+}
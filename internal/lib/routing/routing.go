@@ -0,0 +1,244 @@
+// Package routing picks a per-request target for links with conditional
+// rules.
+//
+// A rule matches when all of its conditions match:
+//   - Platform is derived from the User-Agent: ios, android, windows,
+//     macos or linux, or one of the groups mobile and desktop.
+//   - Languages holds language tags compared with the most preferred
+//     language in Accept-Language. "pt" matches "pt" and "pt-BR",
+//     "pt-BR" only matches "pt-BR".
+//   - Headers maps header names to values compared case-insensitively;
+//     the value "*" only requires the header to be present.
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/text/language"
+
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+
+	// Groups usable in rules only.
+	PlatformMobile  = "mobile"
+	PlatformDesktop = "desktop"
+
+	// MaxRules bounds the number of rules per link.
+	MaxRules = 20
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+var platforms = map[string]struct{}{
+	PlatformIOS:     {},
+	PlatformAndroid: {},
+	PlatformWindows: {},
+	PlatformMacOS:   {},
+	PlatformLinux:   {},
+	PlatformMobile:  {},
+	PlatformDesktop: {},
+}
+
+// Match returns the first rule matching r.
+func Match(rules []storage.Rule, r *http.Request) (storage.Rule, bool) {
+	if len(rules) == 0 {
+		return storage.Rule{}, false
+	}
+
+	ua := r.UserAgent()
+	platform := Platform(ua)
+	lang := PreferredLanguage(r.Header.Get("Accept-Language"))
+
+	for _, rule := range rules {
+		if rule.Platform != "" && !matchPlatform(rule.Platform, platform, ua) {
+			continue
+		}
+		if len(rule.Languages) > 0 && !matchLanguage(rule.Languages, lang) {
+			continue
+		}
+		if !matchHeaders(rule.Headers, r.Header) {
+			continue
+		}
+		return rule, true
+	}
+
+	return storage.Rule{}, false
+}
+
+// Platform detects the operating system from a User-Agent, returning ""
+// when it is unknown.
+func Platform(ua string) string {
+	switch {
+	// iOS user agents also contain "Mac OS X", Android ones "Linux".
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		return PlatformIOS
+	case strings.Contains(ua, "Android"):
+		return PlatformAndroid
+	case strings.Contains(ua, "Windows"):
+		return PlatformWindows
+	case strings.Contains(ua, "Macintosh") || strings.Contains(ua, "Mac OS X"):
+		return PlatformMacOS
+	case strings.Contains(ua, "Linux") && !strings.Contains(ua, "CrOS"):
+		return PlatformLinux
+	default:
+		return ""
+	}
+}
+
+func matchPlatform(want, platform, ua string) bool {
+	switch strings.ToLower(want) {
+	case PlatformMobile:
+		return platform == PlatformIOS || platform == PlatformAndroid || strings.Contains(ua, "Mobile")
+	case PlatformDesktop:
+		return (platform == PlatformWindows || platform == PlatformMacOS || platform == PlatformLinux) &&
+			!strings.Contains(ua, "Mobile")
+	default:
+		return strings.EqualFold(want, platform)
+	}
+}
+
+// PreferredLanguage returns the lowercased language with the highest
+// quality in an Accept-Language header, or "" if there is none.
+func PreferredLanguage(header string) string {
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		// Ties keep the earlier entry.
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+
+	return strings.ToLower(best)
+}
+
+func matchLanguage(want []string, lang string) bool {
+	if lang == "" {
+		return false
+	}
+
+	for _, w := range want {
+		w = strings.ToLower(w)
+		if lang == w || strings.HasPrefix(lang, w+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchHeaders(want map[string]string, h http.Header) bool {
+	for name, value := range want {
+		got := h.Values(name)
+		if len(got) == 0 {
+			return false
+		}
+		if value == "*" {
+			continue
+		}
+
+		found := false
+		for _, g := range got {
+			if strings.EqualFold(strings.TrimSpace(g), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks rules before they are stored. Targets are only checked
+// to be absolute URLs; the URL policy is up to the caller.
+func Validate(rules []storage.Rule) error {
+	if len(rules) > MaxRules {
+		return fmt.Errorf("%w: at most %d rules are allowed", ErrInvalidRule, MaxRules)
+	}
+
+	for i, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckTargets applies the URL policy to every rule target.
+func CheckTargets(ctx context.Context, policy urlpolicy.Policy, rules []storage.Rule) error {
+	for i, rule := range rules {
+		if err := policy.Check(ctx, rule.Target); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule storage.Rule) error {
+	if rule.Platform == "" && len(rule.Languages) == 0 && len(rule.Headers) == 0 {
+		return fmt.Errorf("%w: at least one condition is required", ErrInvalidRule)
+	}
+
+	if rule.Platform != "" {
+		if _, ok := platforms[strings.ToLower(rule.Platform)]; !ok {
+			return fmt.Errorf("%w: unknown platform %q", ErrInvalidRule, rule.Platform)
+		}
+	}
+
+	for _, l := range rule.Languages {
+		if _, err := language.Parse(l); err != nil {
+			return fmt.Errorf("%w: invalid language %q", ErrInvalidRule, l)
+		}
+	}
+
+	for name, value := range rule.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidRule, name)
+		}
+		if value == "" || !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("%w: invalid value for header %q", ErrInvalidRule, name)
+		}
+	}
+
+	u, err := url.Parse(rule.Target)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("%w: target must be an absolute url", ErrInvalidRule)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package routing
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/storage"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	uaWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	uaMac     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15"
+	uaLinux   = "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+)
+
+func TestPlatform(t *testing.T) {
+	assert.Equal(t, PlatformIOS, Platform(uaIPhone))
+	assert.Equal(t, PlatformAndroid, Platform(uaAndroid))
+	assert.Equal(t, PlatformWindows, Platform(uaWindows))
+	assert.Equal(t, PlatformMacOS, Platform(uaMac))
+	assert.Equal(t, PlatformLinux, Platform(uaLinux))
+	assert.Equal(t, "", Platform("curl/8.0"))
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                           "",
+		"de":                         "de",
+		"en-US,en;q=0.9":             "en-us",
+		"fr;q=0.5, pt-BR;q=0.8, *":   "pt-br",
+		"*, es":                      "es",
+		"ru;q=0, it;q=bad, nl;q=0.1": "nl",
+		"de;q=0.7, fr;q=0.7":         "de",
+	}
+
+	for header, want := range tests {
+		assert.Equal(t, want, PreferredLanguage(header), header)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rules := []storage.Rule{
+		{Platform: "ios", Target: "ios"},
+		{Platform: "Mobile", Languages: []string{"pt"}, Target: "mobile-pt"},
+		{Platform: "desktop", Headers: map[string]string{"X-Channel": "Beta"}, Target: "desktop-beta"},
+		{Languages: []string{"pt-BR", "es"}, Target: "pt-br-es"},
+		{Headers: map[string]string{"X-Debug": "*"}, Target: "debug"},
+	}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "first match wins", headers: map[string]string{"User-Agent": uaIPhone, "Accept-Language": "pt"}, want: "ios"},
+		{name: "mobile group", headers: map[string]string{"User-Agent": uaAndroid, "Accept-Language": "pt-PT"}, want: "mobile-pt"},
+		{name: "desktop with header", headers: map[string]string{"User-Agent": uaMac, "X-Channel": "beta"}, want: "desktop-beta"},
+		{name: "desktop without header", headers: map[string]string{"User-Agent": uaMac}, want: ""},
+		{name: "region must match", headers: map[string]string{"Accept-Language": "pt-PT"}, want: ""},
+		{name: "region", headers: map[string]string{"Accept-Language": "en;q=0.5, pt-BR"}, want: "pt-br-es"},
+		{name: "header presence", headers: map[string]string{"X-Debug": "0"}, want: "debug"},
+		{name: "no match", headers: map[string]string{"User-Agent": uaLinux}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			rule, ok := Match(rules, r)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, rule.Target)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    storage.Rule
+		wantErr bool
+	}{
+		{name: "valid", rule: storage.Rule{Platform: "android", Languages: []string{"de-AT"}, Headers: map[string]string{"X-A": "*"}, Target: "https://example.com"}},
+		{name: "no conditions", rule: storage.Rule{Target: "https://example.com"}, wantErr: true},
+		{name: "unknown platform", rule: storage.Rule{Platform: "symbian", Target: "https://example.com"}, wantErr: true},
+		{name: "bad language", rule: storage.Rule{Languages: []string{"not a tag"}, Target: "https://example.com"}, wantErr: true},
+		{name: "bad header name", rule: storage.Rule{Headers: map[string]string{"X A": "1"}, Target: "https://example.com"}, wantErr: true},
+		{name: "empty header value", rule: storage.Rule{Headers: map[string]string{"X-A": ""}, Target: "https://example.com"}, wantErr: true},
+		{name: "relative target", rule: storage.Rule{Platform: "ios", Target: "/app"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]storage.Rule{tt.rule})
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	tooMany := make([]storage.Rule, MaxRules+1)
+	assert.ErrorIs(t, Validate(tooMany), ErrInvalidRule)
+}
@@ -0,0 +1,87 @@
+// Package schedule evaluates time-based target switching of links.
+//
+// Entries are kept sorted by time. Before the first entry the link's own
+// target is used; afterwards the target of the latest entry that has
+// started.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+// MaxEntries bounds the schedule of a link.
+const MaxEntries = 50
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Target returns the target scheduled at now, if any entry has started.
+func Target(entries []storage.ScheduleEntry, now time.Time) (string, bool) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].At.After(now) {
+			return entries[i].Target, true
+		}
+	}
+	return "", false
+}
+
+// Next returns when the target changes after now, if it does.
+func Next(entries []storage.ScheduleEntry, now time.Time) (time.Time, bool) {
+	for _, e := range entries {
+		if e.At.After(now) {
+			return e.At, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Normalize validates entries and returns them sorted by time in UTC.
+// Targets are only checked to be absolute URLs; the URL policy is up to
+// the caller.
+func Normalize(entries []storage.ScheduleEntry) ([]storage.ScheduleEntry, error) {
+	if len(entries) > MaxEntries {
+		return nil, fmt.Errorf("%w: at most %d entries are allowed", ErrInvalidSchedule, MaxEntries)
+	}
+
+	out := make([]storage.ScheduleEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.At.IsZero() {
+			return nil, fmt.Errorf("%w: entry time is required", ErrInvalidSchedule)
+		}
+
+		u, err := url.Parse(e.Target)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("%w: target of the entry at %s must be an absolute url",
+				ErrInvalidSchedule, e.At.Format(time.RFC3339))
+		}
+
+		out = append(out, storage.ScheduleEntry{At: e.At.UTC(), Target: e.Target})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+
+	for i := 1; i < len(out); i++ {
+		if out[i].At.Equal(out[i-1].At) {
+			return nil, fmt.Errorf("%w: two entries at %s", ErrInvalidSchedule, out[i].At.Format(time.RFC3339))
+		}
+	}
+
+	return out, nil
+}
+
+// CheckTargets applies the URL policy to every scheduled target.
+func CheckTargets(ctx context.Context, policy urlpolicy.Policy, entries []storage.ScheduleEntry) error {
+	for _, e := range entries {
+		if err := policy.Check(ctx, e.Target); err != nil {
+			return fmt.Errorf("entry at %s: %w", e.At.Format(time.RFC3339), err)
+		}
+	}
+	return nil
+}
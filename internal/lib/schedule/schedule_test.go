@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+)
+
+func TestTargetAndNext(t *testing.T) {
+	launch := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := []storage.ScheduleEntry{
+		{At: launch, Target: "https://example.com/product"},
+		{At: launch.Add(30 * 24 * time.Hour), Target: "https://example.com/archive"},
+	}
+
+	_, ok := Target(entries, launch.Add(-time.Second))
+	assert.False(t, ok)
+	next, ok := Next(entries, launch.Add(-time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, launch, next)
+
+	target, ok := Target(entries, launch)
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/product", target)
+
+	target, ok = Target(entries, launch.Add(365*24*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/archive", target)
+	_, ok = Next(entries, launch.Add(365*24*time.Hour))
+	assert.False(t, ok)
+}
+
+func TestNormalize(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, moscow)
+	second := first.Add(time.Hour)
+
+	got, err := Normalize([]storage.ScheduleEntry{
+		{At: second, Target: "https://example.com/b"},
+		{At: first, Target: "https://example.com/a"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.ScheduleEntry{
+		{At: first.UTC(), Target: "https://example.com/a"},
+		{At: second.UTC(), Target: "https://example.com/b"},
+	}, got)
+
+	tests := map[string][]storage.ScheduleEntry{
+		"missing time":    {{Target: "https://example.com"}},
+		"relative target": {{At: first, Target: "/a"}},
+		"same time": {
+			{At: first, Target: "https://example.com/a"},
+			{At: first.UTC(), Target: "https://example.com/b"},
+		},
+		"too many": make([]storage.ScheduleEntry, MaxEntries+1),
+	}
+	for name, entries := range tests {
+		_, err := Normalize(entries)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, name)
+	}
+}
@@ -0,0 +1,116 @@
+// Package split picks a variant of an A/B split by weight.
+package split
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"regexp"
+
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+const (
+	MaxVariants = 10
+	MaxWeight   = 1000
+)
+
+var ErrInvalidSplit = errors.New("invalid split")
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// Pick chooses a variant with probability proportional to its weight.
+// intN must return a number in [0, n); nil means math/rand/v2.IntN.
+// Pick reports false when no variant has a positive weight.
+func Pick(variants []storage.Variant, intN func(n int) int) (storage.Variant, bool) {
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return storage.Variant{}, false
+	}
+
+	if intN == nil {
+		intN = rand.IntN
+	}
+
+	n := intN(total)
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if n < v.Weight {
+			return v, true
+		}
+		n -= v.Weight
+	}
+
+	// Unreachable with a well-behaved intN.
+	return storage.Variant{}, false
+}
+
+// Find returns the variant called name if it still receives traffic.
+func Find(variants []storage.Variant, name string) (storage.Variant, bool) {
+	for _, v := range variants {
+		if v.Name == name && v.Weight > 0 {
+			return v, true
+		}
+	}
+	return storage.Variant{}, false
+}
+
+// Validate checks a split before it is stored. An empty split is valid
+// and turns the experiment off. Targets are only checked to be absolute
+// URLs; the URL policy is up to the caller.
+func Validate(s storage.Split) error {
+	if len(s.Variants) == 0 {
+		return nil
+	}
+	if len(s.Variants) > MaxVariants {
+		return fmt.Errorf("%w: at most %d variants are allowed", ErrInvalidSplit, MaxVariants)
+	}
+
+	names := make(map[string]struct{}, len(s.Variants))
+	total := 0
+	for _, v := range s.Variants {
+		if !validName.MatchString(v.Name) {
+			return fmt.Errorf("%w: invalid variant name %q", ErrInvalidSplit, v.Name)
+		}
+		if _, ok := names[v.Name]; ok {
+			return fmt.Errorf("%w: duplicate variant %q", ErrInvalidSplit, v.Name)
+		}
+		names[v.Name] = struct{}{}
+
+		if v.Weight < 0 || v.Weight > MaxWeight {
+			return fmt.Errorf("%w: weight of %q must be between 0 and %d", ErrInvalidSplit, v.Name, MaxWeight)
+		}
+		total += v.Weight
+
+		u, err := url.Parse(v.Target)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("%w: target of %q must be an absolute url", ErrInvalidSplit, v.Name)
+		}
+	}
+
+	if total == 0 {
+		return fmt.Errorf("%w: at least one variant needs a positive weight", ErrInvalidSplit)
+	}
+
+	return nil
+}
+
+// CheckTargets applies the URL policy to every variant target.
+func CheckTargets(ctx context.Context, policy urlpolicy.Policy, s storage.Split) error {
+	for _, v := range s.Variants {
+		if err := policy.Check(ctx, v.Target); err != nil {
+			return fmt.Errorf("variant %q: %w", v.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/storage"
+)
+
+func TestPick(t *testing.T) {
+	variants := []storage.Variant{
+		{Name: "a", Weight: 1},
+		{Name: "off", Weight: 0},
+		{Name: "b", Weight: 3},
+	}
+
+	// Every draw in [0, 4) maps to exactly one variant.
+	want := []string{"a", "b", "b", "b"}
+	for n, name := range want {
+		v, ok := Pick(variants, func(total int) int {
+			assert.Equal(t, 4, total)
+			return n
+		})
+		assert.True(t, ok)
+		assert.Equal(t, name, v.Name)
+	}
+
+	_, ok := Pick([]storage.Variant{{Name: "off"}}, nil)
+	assert.False(t, ok)
+}
+
+func TestPick_Distribution(t *testing.T) {
+	variants := []storage.Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 9}}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		v, _ := Pick(variants, nil)
+		counts[v.Name]++
+	}
+
+	assert.InDelta(t, 1000, counts["a"], 200)
+	assert.InDelta(t, 9000, counts["b"], 200)
+}
+
+func TestFind(t *testing.T) {
+	variants := []storage.Variant{{Name: "a", Weight: 1}, {Name: "off", Weight: 0}}
+
+	_, ok := Find(variants, "a")
+	assert.True(t, ok)
+	_, ok = Find(variants, "off")
+	assert.False(t, ok)
+	_, ok = Find(variants, "gone")
+	assert.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	target := "https://example.com"
+
+	tests := []struct {
+		name    string
+		split   storage.Split
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "valid", split: storage.Split{Variants: []storage.Variant{{Name: "a", Target: target, Weight: 50}, {Name: "b", Target: target}}}},
+		{name: "bad name", split: storage.Split{Variants: []storage.Variant{{Name: "a b", Target: target, Weight: 1}}}, wantErr: true},
+		{name: "duplicate", split: storage.Split{Variants: []storage.Variant{{Name: "a", Target: target, Weight: 1}, {Name: "a", Target: target, Weight: 1}}}, wantErr: true},
+		{name: "negative weight", split: storage.Split{Variants: []storage.Variant{{Name: "a", Target: target, Weight: -1}}}, wantErr: true},
+		{name: "all off", split: storage.Split{Variants: []storage.Variant{{Name: "a", Target: target}}}, wantErr: true},
+		{name: "relative target", split: storage.Split{Variants: []storage.Variant{{Name: "a", Target: "/x", Weight: 1}}}, wantErr: true},
+		{name: "too many", split: storage.Split{Variants: make([]storage.Variant, MaxVariants+1)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.split)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSplit)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
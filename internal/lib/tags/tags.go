@@ -0,0 +1,78 @@
+// Package tags normalizes link tags and validates free-form link metadata.
+package tags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxTags bounds the number of tags per link.
+	MaxTags = 20
+
+	MaxMetadataKeys = 50
+	// MaxMetadataSize bounds the encoded size of all metadata values.
+	MaxMetadataSize = 16 << 10
+)
+
+var (
+	ErrInvalidTag      = errors.New("invalid tag")
+	ErrInvalidMetadata = errors.New("invalid metadata")
+)
+
+// Dots are not allowed: middleware.URLFormat would cut them off tags in
+// paths like /url/{alias}/tags/{tag}.
+var validTag = regexp.MustCompile(`^[a-z0-9][a-z0-9_:-]{0,63}$`)
+
+// Normalize lowercases and trims tags and drops duplicates, keeping
+// the original order.
+func Normalize(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !validTag.MatchString(t) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, t)
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidTag, MaxTags)
+	}
+
+	return out, nil
+}
+
+// ValidateMetadata checks the limits on metadata. Values may be any JSON.
+func ValidateMetadata(metadata map[string]json.RawMessage) error {
+	if len(metadata) > MaxMetadataKeys {
+		return fmt.Errorf("%w: at most %d keys are allowed", ErrInvalidMetadata, MaxMetadataKeys)
+	}
+
+	size := 0
+	for key, value := range metadata {
+		if key == "" || len(key) > 64 {
+			return fmt.Errorf("%w: keys must be 1 to 64 bytes long", ErrInvalidMetadata)
+		}
+		size += len(key) + len(value)
+	}
+
+	if size > MaxMetadataSize {
+		return fmt.Errorf("%w: larger than %d bytes", ErrInvalidMetadata, MaxMetadataSize)
+	}
+
+	return nil
+}
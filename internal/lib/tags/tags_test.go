@@ -0,0 +1,45 @@
+package tags
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize([]string{" Spring-Sale ", "team:growth", "SPRING-SALE", "v1_beta"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"spring-sale", "team:growth", "v1_beta"}, got)
+
+	got, err = Normalize(nil)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	for _, bad := range []string{"", "  ", "-leading", "v1.2", "with space", "ümlaut", strings.Repeat("a", 65)} {
+		_, err := Normalize([]string{bad})
+		assert.ErrorIs(t, err, ErrInvalidTag, bad)
+	}
+
+	many := make([]string, MaxTags+1)
+	for i := range many {
+		many[i] = strings.Repeat("a", i+1)
+	}
+	_, err = Normalize(many)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestValidateMetadata(t *testing.T) {
+	assert.NoError(t, ValidateMetadata(nil))
+	assert.NoError(t, ValidateMetadata(map[string]json.RawMessage{
+		"owner": json.RawMessage(`"growth"`),
+		"utm":   json.RawMessage(`{"source":"newsletter"}`),
+	}))
+
+	assert.ErrorIs(t, ValidateMetadata(map[string]json.RawMessage{"": json.RawMessage(`1`)}), ErrInvalidMetadata)
+	assert.ErrorIs(t, ValidateMetadata(map[string]json.RawMessage{
+		"big": json.RawMessage(`"` + strings.Repeat("x", MaxMetadataSize) + `"`),
+	}), ErrInvalidMetadata)
+}
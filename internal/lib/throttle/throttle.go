@@ -0,0 +1,89 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of tracked keys after which expired
+// entries are dropped on the next attempt, keeping memory bounded.
+const sweepThreshold = 1024
+
+// Limiter locks a key out for a while after too many failures,
+// e.g. wrong passwords from one client.
+type Limiter struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	entries     map[string]*entry
+	now         func() time.Time
+}
+
+type entry struct {
+	failures    int
+	expires     time.Time
+	lockedUntil time.Time
+}
+
+// New allows maxFailures failures per key within window. Once the limit
+// is reached the key is locked for window.
+func New(maxFailures int, window time.Duration) *Limiter {
+	return &Limiter{
+		maxFailures: maxFailures,
+		window:      window,
+		entries:     make(map[string]*entry),
+		now:         time.Now,
+	}
+}
+
+// Allow reports whether the key may try again and, if not, how long
+// it has to wait. An allowed attempt counts as a failure until Reset,
+// so concurrent attempts cannot get past the limit while the first ones
+// are still being checked.
+func (l *Limiter) Allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	e, ok := l.entries[key]
+	if ok {
+		if wait := e.lockedUntil.Sub(now); wait > 0 {
+			return wait, false
+		}
+	}
+
+	if len(l.entries) >= sweepThreshold {
+		l.sweep(now)
+	}
+
+	if !ok || now.After(e.expires) {
+		e = &entry{}
+		l.entries[key] = e
+	}
+
+	e.failures++
+	e.expires = now.Add(l.window)
+	if e.failures >= l.maxFailures {
+		e.lockedUntil = now.Add(l.window)
+		e.failures = 0
+	}
+
+	return 0, true
+}
+
+// Reset forgets the attempts of the key, e.g. after a correct password.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if now.After(e.expires) && now.After(e.lockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package throttle
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, ok := l.Allow("a")
+		assert.True(t, ok)
+	}
+
+	wait, ok := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, wait)
+
+	// Other keys are not affected.
+	_, ok = l.Allow("b")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute + time.Second)
+	_, ok = l.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestLimiter_AttemptsExpire(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	now = now.Add(2 * time.Minute)
+	l.Allow("a")
+
+	_, ok := l.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestLimiter_Reset(t *testing.T) {
+	l := New(1, time.Minute)
+
+	l.Allow("a")
+	_, ok := l.Allow("a")
+	assert.False(t, ok)
+
+	l.Reset("a")
+	_, ok = l.Allow("a")
+	assert.True(t, ok)
+}
+
+func TestLimiter_Concurrent(t *testing.T) {
+	l := New(3, time.Minute)
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := l.Allow("a"); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), allowed.Load())
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(5, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < sweepThreshold; i++ {
+		l.Allow(string(rune('a' + i)))
+	}
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("fresh")
+
+	assert.Len(t, l.entries, 1)
+}
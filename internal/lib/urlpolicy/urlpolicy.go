@@ -0,0 +1,341 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid url")
+	ErrSchemeNotAllowed = errors.New("scheme is not allowed")
+	ErrDomainBlocked    = errors.New("domain is blocked")
+	ErrDomainNotAllowed = errors.New("domain is not in the allowlist")
+	ErrPrivateAddress   = errors.New("url points to a private network")
+	ErrResolveFailed    = errors.New("failed to resolve host")
+)
+
+// Policy decides whether a URL may be used as a link target.
+// Rejections wrap one of the Err* values above.
+type Policy interface {
+	Check(ctx context.Context, rawURL string) error
+}
+
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+type Options struct {
+	// Schemes allowed for targets, e.g. http and https.
+	Schemes []string
+	// ListsFile is a YAML file with "allow" and "block" domain lists.
+	// A domain also matches all of its subdomains. A non-empty allow
+	// list rejects every domain not on it.
+	ListsFile string
+	// DenyPrivate rejects targets that are or resolve to loopback,
+	// private, link-local and other non-public addresses.
+	DenyPrivate bool
+	// Resolver defaults to net.DefaultResolver.
+	Resolver       Resolver
+	ResolveTimeout time.Duration
+}
+
+type lists struct {
+	Allow []string `yaml:"allow"`
+	Block []string `yaml:"block"`
+}
+
+// Checker is the default Policy.
+type Checker struct {
+	schemes        map[string]struct{}
+	listsFile      string
+	denyPrivate    bool
+	resolver       Resolver
+	resolveTimeout time.Duration
+
+	lists   atomic.Pointer[lists]
+	modTime time.Time
+}
+
+func New(opts Options) (*Checker, error) {
+	const op = "urlpolicy.New"
+
+	c := &Checker{
+		schemes:        make(map[string]struct{}, len(opts.Schemes)),
+		listsFile:      opts.ListsFile,
+		denyPrivate:    opts.DenyPrivate,
+		resolver:       opts.Resolver,
+		resolveTimeout: opts.ResolveTimeout,
+	}
+	for _, s := range opts.Schemes {
+		c.schemes[strings.ToLower(s)] = struct{}{}
+	}
+	if c.resolver == nil {
+		c.resolver = net.DefaultResolver
+	}
+	c.lists.Store(&lists{})
+
+	if c.listsFile != "" {
+		if _, err := c.Reload(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Checker) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if _, ok := c.schemes[scheme]; !ok {
+		return fmt.Errorf("%w: %q", ErrSchemeNotAllowed, scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: host is empty", ErrInvalidURL)
+	}
+
+	l := c.lists.Load()
+	if matchDomain(host, l.Block) {
+		return fmt.Errorf("%w: %s", ErrDomainBlocked, host)
+	}
+	if len(l.Allow) > 0 && !matchDomain(host, l.Allow) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+
+	if c.denyPrivate {
+		return c.checkAddress(ctx, host)
+	}
+
+	return nil
+}
+
+// checkAddress rejects hosts that are or resolve to non-public addresses.
+// Hosts that do not exist are let through: nothing proves they are
+// private, and the target may simply not exist yet. Any other lookup
+// failure, e.g. a timeout, rejects the host, since it might resolve to a
+// private address on the next try.
+func (c *Checker) checkAddress(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+		return nil
+	}
+	if addr, ok := parseLegacyIPv4(host); ok {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s is %s", ErrPrivateAddress, host, addr)
+		}
+		return nil
+	}
+
+	if c.resolveTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.resolveTimeout)
+		defer cancel()
+	}
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil
+		}
+		return fmt.Errorf("%w: %s: %w", ErrResolveFailed, host, err)
+	}
+
+	for _, a := range addrs {
+		addr, ok := netip.AddrFromSlice(a.IP)
+		if ok && !isPublic(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateAddress, host, addr.Unmap())
+		}
+	}
+
+	return nil
+}
+
+// parseLegacyIPv4 parses the IPv4 forms of inet_aton that netip rejects
+// but browsers and resolvers accept: fewer than four parts, as in 127.1,
+// and hex or octal parts, as in 0x7f000001 or 0177.0.0.1. The last part
+// fills the remaining bytes. Such hosts never reach DNS.
+func parseLegacyIPv4(host string) (netip.Addr, bool) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+
+	var ip uint32
+	for i, part := range parts {
+		base := 10
+		switch {
+		case strings.HasPrefix(part, "0x"):
+			part, base = part[2:], 16
+		case len(part) > 1 && part[0] == '0':
+			part, base = part[1:], 8
+		}
+
+		// The last part fills the bytes the others leave, e.g. three for 127.1.
+		bits := 8
+		if i == len(parts)-1 {
+			bits = 8 * (4 - i)
+		}
+
+		n, err := strconv.ParseUint(part, base, bits)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		if i == len(parts)-1 {
+			ip |= uint32(n)
+		} else {
+			ip |= uint32(n) << (24 - 8*i)
+		}
+	}
+
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}), true
+}
+
+// DialControl is a net.Dialer Control hook that refuses connections to
+// non-public addresses. It runs on the address actually dialed, so a host
+// that passed Check cannot be re-resolved to a private address between
+// the check and the connection.
+func DialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, address)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, address)
+	}
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr.Unmap())
+	}
+	return nil
+}
+
+// Reload re-reads the lists file if it changed since the last load.
+// It reports whether the lists were replaced.
+func (c *Checker) Reload() (bool, error) {
+	const op = "urlpolicy.Reload"
+
+	info, err := os.Stat(c.listsFile)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(c.listsFile)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var l lists
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	l.Allow = normalize(l.Allow)
+	l.Block = normalize(l.Block)
+
+	c.lists.Store(&l)
+	c.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// Watch reloads the lists file every interval until ctx is done. A broken
+// file is logged and the previous lists stay in effect.
+func (c *Checker) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	if c.listsFile == "" || interval <= 0 {
+		return
+	}
+
+	log = log.With(slog.String("component", "urlpolicy"), slog.String("file", c.listsFile))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := c.Reload()
+			if err != nil {
+				log.Error("failed to reload url lists", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("url lists reloaded")
+			}
+		}
+	}
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(domains []string) []string {
+	out := domains[:0]
+	for _, d := range domains {
+		d = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// nonPublic complements the netip.Addr predicates used in isPublic.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+
+	for _, p := range nonPublic {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
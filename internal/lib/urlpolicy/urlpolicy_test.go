@@ -0,0 +1,190 @@
+package urlpolicy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := f[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+	}
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestChecker(t *testing.T) {
+	listsFile := filepath.Join(t.TempDir(), "lists.yaml")
+	require.NoError(t, os.WriteFile(listsFile, []byte("block:\n  - Evil.example\n"), 0o600))
+
+	c, err := New(Options{
+		Schemes:     []string{"http", "https"},
+		ListsFile:   listsFile,
+		DenyPrivate: true,
+		Resolver: fakeResolver{
+			"example.com":       {"93.184.216.34"},
+			"internal.example":  {"93.184.216.34", "10.0.0.5"},
+			"metadata.internal": {"169.254.169.254"},
+			"slow.example":      {},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://example.com/docs"},
+		{url: "HTTP://EXAMPLE.COM"},
+		{url: "https://unresolvable.example"},
+		{url: "javascript:alert(1)", wantErr: ErrSchemeNotAllowed},
+		{url: "file:///etc/passwd", wantErr: ErrSchemeNotAllowed},
+		{url: "ftp://example.com", wantErr: ErrSchemeNotAllowed},
+		{url: "https:///path", wantErr: ErrInvalidURL},
+		{url: "https://evil.example", wantErr: ErrDomainBlocked},
+		{url: "https://sub.evil.example/x", wantErr: ErrDomainBlocked},
+		{url: "https://notevil.example", wantErr: nil},
+		{url: "http://127.0.0.1:8080", wantErr: ErrPrivateAddress},
+		{url: "http://169.254.169.254/latest/meta-data", wantErr: ErrPrivateAddress},
+		{url: "http://[::1]/", wantErr: ErrPrivateAddress},
+		{url: "http://[::ffff:10.0.0.1]/", wantErr: ErrPrivateAddress},
+		{url: "http://100.64.0.1/", wantErr: ErrPrivateAddress},
+		{url: "http://internal.example", wantErr: ErrPrivateAddress},
+		{url: "http://metadata.internal", wantErr: ErrPrivateAddress},
+		{url: "http://slow.example", wantErr: ErrResolveFailed},
+		{url: "http://127.1/", wantErr: ErrPrivateAddress},
+		{url: "http://2130706433/", wantErr: ErrPrivateAddress},
+		{url: "http://0x7f000001/", wantErr: ErrPrivateAddress},
+		{url: "http://0x7F.1/", wantErr: ErrPrivateAddress},
+		{url: "http://0177.0.0.1/", wantErr: ErrPrivateAddress},
+		{url: "http://10.1.1/", wantErr: ErrPrivateAddress},
+		{url: "http://0/", wantErr: ErrPrivateAddress},
+		{url: "http://1572395042/"},
+		{url: "http://cafe.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := c.Check(context.Background(), tt.url)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestChecker_AllowList(t *testing.T) {
+	listsFile := filepath.Join(t.TempDir(), "lists.yaml")
+	require.NoError(t, os.WriteFile(listsFile, []byte("allow: [example.com]\n"), 0o600))
+
+	c, err := New(Options{Schemes: []string{"https"}, ListsFile: listsFile})
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Check(context.Background(), "https://docs.example.com"))
+	assert.ErrorIs(t, c.Check(context.Background(), "https://example.org"), ErrDomainNotAllowed)
+}
+
+func TestChecker_Reload(t *testing.T) {
+	listsFile := filepath.Join(t.TempDir(), "lists.yaml")
+	require.NoError(t, os.WriteFile(listsFile, []byte("block: []\n"), 0o600))
+
+	c, err := New(Options{Schemes: []string{"https"}, ListsFile: listsFile})
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Check(context.Background(), "https://evil.example"))
+
+	reloaded, err := c.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	require.NoError(t, os.WriteFile(listsFile, []byte("block: [evil.example]\n"), 0o600))
+	// Make sure the modification time differs on coarse file systems.
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(listsFile, later, later))
+
+	reloaded, err = c.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	assert.ErrorIs(t, c.Check(context.Background(), "https://evil.example"), ErrDomainBlocked)
+
+	// A broken file keeps the previous lists.
+	require.NoError(t, os.WriteFile(listsFile, []byte("block: [\n"), 0o600))
+	later = later.Add(time.Second)
+	require.NoError(t, os.Chtimes(listsFile, later, later))
+
+	_, err = c.Reload()
+	require.Error(t, err)
+	assert.ErrorIs(t, c.Check(context.Background(), "https://evil.example"), ErrDomainBlocked)
+}
+
+func TestDialControl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address must not be contacted")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: (&net.Dialer{Control: DialControl}).DialContext,
+	}}
+
+	_, err := client.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+
+	assert.NoError(t, DialControl("tcp4", "93.184.216.34:443", nil))
+	assert.ErrorIs(t, DialControl("tcp6", "[::ffff:10.0.0.1]:80", nil), ErrPrivateAddress)
+}
+
+func TestParseLegacyIPv4(t *testing.T) {
+	tests := []struct {
+		host   string
+		want   string
+		wantOK bool
+	}{
+		{host: "127.1", want: "127.0.0.1", wantOK: true},
+		{host: "127.0.1", want: "127.0.0.1", wantOK: true},
+		{host: "2130706433", want: "127.0.0.1", wantOK: true},
+		{host: "0x7f000001", want: "127.0.0.1", wantOK: true},
+		{host: "0177.0.0.01", want: "127.0.0.1", wantOK: true},
+		{host: "0xa.0x1.0.010", want: "10.1.0.8", wantOK: true},
+		{host: "192.168.65535", want: "192.168.255.255", wantOK: true},
+		{host: "192.168.65536"},
+		{host: "256.0.0.1"},
+		{host: "1.2.3.4.5"},
+		{host: "1..2"},
+		{host: "0x"},
+		{host: "08"},
+		{host: "cafe"},
+		{host: "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, ok := parseLegacyIPv4(tt.host)
+			require.Equal(t, tt.wantOK, ok)
+			if ok {
+				assert.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Package urlpolicytest provides url policies for tests of code that
+// checks link targets.
+package urlpolicytest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/urlpolicy"
+)
+
+// PublicResolver resolves every host to a public address, so tests do
+// not depend on DNS.
+type PublicResolver struct{}
+
+func (PublicResolver) LookupIPAddr(context.Context, string) ([]net.IPAddr, error) {
+	return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+}
+
+// New returns a policy allowing http and https targets that are not
+// literal private addresses.
+func New(t testing.TB) urlpolicy.Policy {
+	t.Helper()
+
+	policy, err := urlpolicy.New(urlpolicy.Options{
+		Schemes:     []string{"http", "https"},
+		DenyPrivate: true,
+		Resolver:    PublicResolver{},
+	})
+	require.NoError(t, err)
+
+	return policy
+}
@@ -0,0 +1,329 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+const (
+	userAgent = "url-shortener-metadata/1.0"
+
+	maxRedirects = 5
+	// Longer values are cut; they only have to fit a listing.
+	maxTitleLen       = 512
+	maxDescriptionLen = 1024
+	maxURLLen         = 2048
+)
+
+var (
+	ErrBadStatus   = errors.New("unexpected status")
+	ErrNotHTML     = errors.New("target is not an html page")
+	ErrQueueFull   = errors.New("fetch queue is full")
+	errTooManyHops = errors.New("too many redirects")
+)
+
+type Storage interface {
+	SaveMetadata(urlID int64, meta storage.PageMeta) error
+}
+
+type Options struct {
+	// Timeout bounds a single fetch, including redirects and reading the body.
+	Timeout time.Duration
+	// MaxBodySize is how much of the page is read looking for the head.
+	MaxBodySize int64
+	Workers     int
+	QueueSize   int
+	// Policy, when set, is applied to the target and every redirect.
+	Policy urlpolicy.Policy
+}
+
+type job struct {
+	urlID  int64
+	target string
+}
+
+// Fetcher retrieves page metadata for saved links in the background.
+type Fetcher struct {
+	log     *slog.Logger
+	storage Storage
+	client  *http.Client
+	opts    Options
+	queue   chan job
+	now     func() time.Time
+}
+
+func New(log *slog.Logger, storage Storage, client *http.Client, opts Options) *Fetcher {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 512 << 10
+	}
+
+	// The client is copied so that the redirect check does not leak into
+	// other users of it.
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errTooManyHops
+		}
+		if opts.Policy != nil {
+			return opts.Policy.Check(req.Context(), req.URL.String())
+		}
+		return nil
+	}
+
+	return &Fetcher{
+		log:     log.With(slog.String("component", "metadata")),
+		storage: storage,
+		client:  &c,
+		opts:    opts,
+		queue:   make(chan job, opts.QueueSize),
+		now:     time.Now,
+	}
+}
+
+// Enqueue schedules a fetch for the link. It never blocks: when the queue
+// is full the link is skipped and ErrQueueFull returned.
+func (f *Fetcher) Enqueue(urlID int64, target string) error {
+	select {
+	case f.queue <- job{urlID: urlID, target: target}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run processes the queue with Options.Workers goroutines until ctx is done.
+func (f *Fetcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < f.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-f.queue:
+					f.fetchAndSave(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (f *Fetcher) fetchAndSave(ctx context.Context, j job) {
+	log := f.log.With(slog.Int64("url_id", j.urlID), slog.String("url", j.target))
+
+	meta, err := f.Fetch(ctx, j.target)
+	if err != nil {
+		log.Info("failed to fetch metadata", sl.Err(err))
+		return
+	}
+	meta.FetchedAt = f.now()
+
+	if err := f.storage.SaveMetadata(j.urlID, meta); err != nil {
+		log.Error("failed to save metadata", sl.Err(err))
+	}
+}
+
+// Fetch downloads the head of the target page and extracts its metadata.
+// Relative image and favicon URLs are resolved against the final URL.
+func (f *Fetcher) Fetch(ctx context.Context, target string) (storage.PageMeta, error) {
+	const op = "metadata.Fetch"
+
+	if f.opts.Policy != nil {
+		if err := f.opts.Policy.Check(ctx, target); err != nil {
+			return storage.PageMeta{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if f.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return storage.PageMeta{}, fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return storage.PageMeta{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return storage.PageMeta{}, fmt.Errorf("%s: %w: %d", op, ErrBadStatus, resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return storage.PageMeta{}, fmt.Errorf("%s: %w: %q", op, ErrNotHTML, mediaType)
+	}
+
+	meta, err := parse(io.LimitReader(resp.Body, f.opts.MaxBodySize), resp.Request.URL)
+	if err != nil {
+		return storage.PageMeta{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return meta, nil
+}
+
+// parse reads tags up to the end of the head. A truncated page still
+// yields whatever was found before the cut.
+func parse(r io.Reader, base *url.URL) (storage.PageMeta, error) {
+	var (
+		meta                   storage.PageMeta
+		ogTitle, description   string
+		ogDescription, favicon string
+		inTitle                bool
+		title                  strings.Builder
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return storage.PageMeta{}, err
+			}
+			break loop
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+
+			switch string(name) {
+			case "body":
+				break loop
+			case "title":
+				inTitle = tt == html.StartTagToken && title.Len() == 0
+			case "meta":
+				key := strings.ToLower(attrs["property"])
+				if key == "" {
+					key = strings.ToLower(attrs["name"])
+				}
+				content := attrs["content"]
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "description":
+					description = content
+				case "og:image", "og:image:url":
+					if meta.Image == "" {
+						meta.Image = resolve(base, content)
+					}
+				}
+			case "link":
+				if favicon == "" && isIcon(attrs["rel"]) {
+					favicon = resolve(base, attrs["href"])
+				}
+			}
+		}
+	}
+
+	meta.Title = clean(firstNonEmpty(ogTitle, title.String()), maxTitleLen)
+	meta.Description = clean(firstNonEmpty(ogDescription, description), maxDescriptionLen)
+	if favicon == "" {
+		favicon = resolve(base, "/favicon.ico")
+	}
+	meta.Favicon = favicon
+
+	return meta, nil
+}
+
+func isIcon(rel string) bool {
+	for _, v := range strings.Fields(strings.ToLower(rel)) {
+		if v == "icon" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns ref as an absolute http(s) URL, or "" if it is not one.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	s := u.String()
+	if len(s) > maxURLLen {
+		return ""
+	}
+	return s
+}
+
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= max {
+		return s
+	}
+
+	// Cut on a rune boundary.
+	cut := 0
+	for i := range s {
+		if i > max {
+			break
+		}
+		cut = i
+	}
+	return s[:cut]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
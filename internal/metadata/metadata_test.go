@@ -0,0 +1,208 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+type saved struct {
+	urlID int64
+	meta  storage.PageMeta
+}
+
+type fakeStorage chan saved
+
+func (f fakeStorage) SaveMetadata(urlID int64, meta storage.PageMeta) error {
+	f <- saved{urlID: urlID, meta: meta}
+	return nil
+}
+
+// denyPolicy rejects every URL containing the substring.
+type denyPolicy string
+
+func (p denyPolicy) Check(_ context.Context, rawURL string) error {
+	if strings.Contains(rawURL, string(p)) {
+		return errors.New("denied")
+	}
+	return nil
+}
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>  Plain &amp; simple
+	title </title>
+	<meta name="description" content="Plain description">
+	<meta property="og:image" content="/img/og.png">
+	<link rel="shortcut icon" href="static/icon.png">
+</head>
+<body>
+	<meta property="og:title" content="Ignored, it is in the body">
+</body>
+</html>`
+
+const ogPage = `<html><head>
+	<title>Plain title</title>
+	<meta property="og:title" content="OG title">
+	<meta property="og:description" content="OG description">
+	<meta property="og:image" content="https://cdn.example/og.png">
+</head></html>`
+
+func newFetcher(st Storage, opts Options) *Fetcher {
+	return New(slogdiscard.NewDiscardLogger(), st, http.DefaultClient, opts)
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs/page", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, userAgent, r.UserAgent())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, ogPage)
+	})
+	mux.Handle("/moved", http.RedirectHandler("/og", http.StatusFound))
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, "<html><head><!--"+strings.Repeat("x", 4096)+"--><title>Too far</title></head></html>")
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newFetcher(nil, Options{Timeout: 100 * time.Millisecond, MaxBodySize: 1024})
+
+	tests := []struct {
+		path    string
+		want    storage.PageMeta
+		wantErr error
+	}{
+		{
+			path: "/docs/page",
+			want: storage.PageMeta{
+				Title:       "Plain & simple title",
+				Description: "Plain description",
+				Image:       srv.URL + "/img/og.png",
+				Favicon:     srv.URL + "/docs/static/icon.png",
+			},
+		},
+		{
+			path: "/og",
+			want: storage.PageMeta{
+				Title:       "OG title",
+				Description: "OG description",
+				Image:       "https://cdn.example/og.png",
+				Favicon:     srv.URL + "/favicon.ico",
+			},
+		},
+		{
+			path: "/moved",
+			want: storage.PageMeta{
+				Title:       "OG title",
+				Description: "OG description",
+				Image:       "https://cdn.example/og.png",
+				Favicon:     srv.URL + "/favicon.ico",
+			},
+		},
+		{
+			path: "/huge",
+			want: storage.PageMeta{Favicon: srv.URL + "/favicon.ico"},
+		},
+		{path: "/missing", wantErr: ErrBadStatus},
+		{path: "/json", wantErr: ErrNotHTML},
+		{path: "/slow", wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetcher_PolicyAppliesToRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/start", http.RedirectHandler("/internal", http.StatusFound))
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect target must not be requested")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newFetcher(nil, Options{Policy: denyPolicy("/internal")})
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/start")
+	require.Error(t, err)
+}
+
+func TestFetcher_Run(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, ogPage)
+	}))
+	defer srv.Close()
+
+	st := make(fakeStorage, 1)
+	f := newFetcher(st, Options{Workers: 2, QueueSize: 1})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	require.NoError(t, f.Enqueue(42, srv.URL))
+	assert.ErrorIs(t, f.Enqueue(43, srv.URL), ErrQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case s := <-st:
+		assert.Equal(t, int64(42), s.urlID)
+		assert.Equal(t, "OG title", s.meta.Title)
+		assert.Equal(t, now, s.meta.FetchedAt)
+	case <-time.After(5 * time.Second):
+		t.Fatal("metadata was not saved")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestClean(t *testing.T) {
+	assert.Equal(t, "a b", clean("  a \n\t b ", 10))
+	assert.Equal(t, "abc", clean("abcdef", 3))
+	// "é" is two bytes and must not be split.
+	assert.Equal(t, "a", clean("aé", 2))
+}
@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+const apiKeyColumns = "id, name, prefix, hash, scopes, owner_id, created_at, last_used_at, revoked_at"
+
+func (s *Storage) SaveAPIKey(k storage.APIKey) (int64, error) {
+	const op = "storage.sqlite.SaveAPIKey"
+
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO api_key(name, prefix, hash, scopes, owner_id, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		k.Name, k.Prefix, k.Hash, strings.Join(k.Scopes, " "), k.OwnerID, k.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// APIKeyByHash also returns revoked keys; callers check RevokedAt.
+func (s *Storage) APIKeyByHash(hash string) (storage.APIKey, error) {
+	const op = "storage.sqlite.APIKeyByHash"
+
+	k, err := scanAPIKey(s.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_key WHERE hash = ?", hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return k, nil
+}
+
+// ListAPIKeys returns all keys, including revoked ones, newest first.
+func (s *Storage) ListAPIKeys() ([]storage.APIKey, error) {
+	const op = "storage.sqlite.ListAPIKeys"
+
+	rows, err := s.db.Query("SELECT " + apiKeyColumns + " FROM api_key ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	keys := []storage.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key. Revoking it again keeps the first time.
+func (s *Storage) RevokeAPIKey(id int64, at time.Time) error {
+	const op = "storage.sqlite.RevokeAPIKey"
+
+	result, err := s.db.Exec(
+		"UPDATE api_key SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?",
+		at.UTC(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// apiKeyTouchInterval limits how often a busy key's last use is written.
+const apiKeyTouchInterval = time.Minute
+
+// TouchAPIKey records the use of a key. Uses within a minute of the
+// recorded one are not written.
+func (s *Storage) TouchAPIKey(id int64, at time.Time) error {
+	const op = "storage.sqlite.TouchAPIKey"
+
+	if _, err := s.db.Exec(
+		"UPDATE api_key SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		at.UTC(), id, at.Add(-apiKeyTouchInterval).UTC(),
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanAPIKey(row scanner) (storage.APIKey, error) {
+	var (
+		k          storage.APIKey
+		scopes     string
+		lastUsedAt sql.NullTime
+		revokedAt  sql.NullTime
+	)
+
+	if err := row.Scan(
+		&k.ID, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.OwnerID, &k.CreatedAt, &lastUsedAt, &revokedAt,
+	); err != nil {
+		return storage.APIKey{}, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	k.LastUsedAt = lastUsedAt.Time
+	k.RevokedAt = revokedAt.Time
+
+	return k, nil
+}
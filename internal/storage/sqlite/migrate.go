@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type migration func(tx *sql.Tx) error
+
+// migrate brings the schema up to date. The applied version is kept in
+// PRAGMA user_version, so every migration runs exactly once per database.
+// New migrations must only ever be appended to the list.
+func (s *Storage) migrate(defaultDomain string) error {
+	const op = "storage.sqlite.migrate"
+
+	migrations := []migration{
+		execMigration(`
+		CREATE TABLE IF NOT EXISTS url(
+			id INTEGER PRIMARY KEY,
+			alias TEXT NOT NULL UNIQUE,
+			url TEXT NOT NULL
+		)`),
+		migrateDomains(defaultDomain),
+		execMigration("ALTER TABLE url ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 302"),
+		execMigration(
+			"ALTER TABLE url ADD COLUMN forward_query INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE url ADD COLUMN query_precedence TEXT NOT NULL DEFAULT 'target'",
+			"ALTER TABLE url ADD COLUMN forward_path INTEGER NOT NULL DEFAULT 0",
+		),
+		execMigration(
+			"ALTER TABLE url ADD COLUMN title TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE url ADD COLUMN created_at DATETIME",
+			`CREATE TABLE click(
+				id INTEGER PRIMARY KEY,
+				url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+				created_at DATETIME NOT NULL
+			)`,
+			"CREATE INDEX idx_click_url_id ON click(url_id)",
+		),
+		execMigration("ALTER TABLE url ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''"),
+		execMigration(
+			"ALTER TABLE url ADD COLUMN last_status INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE url ADD COLUMN last_check_error TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE url ADD COLUMN last_checked_at DATETIME",
+			"CREATE INDEX idx_url_last_checked_at ON url(last_checked_at)",
+		),
+		execMigration(
+			"ALTER TABLE url ADD COLUMN meta_title TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE url ADD COLUMN meta_description TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE url ADD COLUMN meta_image TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE url ADD COLUMN meta_favicon TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE url ADD COLUMN meta_fetched_at DATETIME",
+		),
+		execMigration("ALTER TABLE url ADD COLUMN rules TEXT NOT NULL DEFAULT ''"),
+		execMigration(
+			"ALTER TABLE url ADD COLUMN split TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE click ADD COLUMN variant TEXT NOT NULL DEFAULT ''",
+		),
+		execMigration(
+			"ALTER TABLE url ADD COLUMN not_before DATETIME",
+			"ALTER TABLE url ADD COLUMN schedule TEXT NOT NULL DEFAULT ''",
+		),
+		execMigration(
+			`CREATE TABLE tag(
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE
+			)`,
+			`CREATE TABLE url_tag(
+				url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+				tag_id INTEGER NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+				PRIMARY KEY(url_id, tag_id)
+			)`,
+			"CREATE INDEX idx_url_tag_tag_id ON url_tag(tag_id)",
+			`CREATE TABLE url_metadata(
+				url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+				key TEXT NOT NULL,
+				value TEXT NOT NULL,
+				PRIMARY KEY(url_id, key)
+			)`,
+		),
+		execMigration("ALTER TABLE url ADD COLUMN notes TEXT NOT NULL DEFAULT ''"),
+		execMigration(
+			`CREATE TABLE api_key(
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				prefix TEXT NOT NULL,
+				hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL,
+				owner_id INTEGER NOT NULL,
+				created_at DATETIME NOT NULL,
+				last_used_at DATETIME,
+				revoked_at DATETIME
+			)`,
+		),
+		execMigration("ALTER TABLE url ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 0"),
+		execMigration(
+			`CREATE TABLE revoked_token(
+				jti TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				revoked_by INTEGER NOT NULL,
+				revoked_at DATETIME NOT NULL,
+				expires_at DATETIME
+			)`,
+			`CREATE TABLE token_cutoff(
+				user_id INTEGER PRIMARY KEY,
+				issued_before DATETIME NOT NULL,
+				set_by INTEGER NOT NULL,
+				set_at DATETIME NOT NULL
+			)`,
+		),
+	}
+
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("%s: read schema version: %w", op, err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("%s: begin: %w", op, err)
+		}
+
+		if err := migrations[i](tx); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s: migration %d: %w", op, i+1, err)
+		}
+
+		// PRAGMA does not accept bound parameters.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s: migration %d: set version: %w", op, i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("%s: migration %d: commit: %w", op, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func execMigration(queries ...string) migration {
+	return func(tx *sql.Tx) error {
+		for _, q := range queries {
+			if _, err := tx.Exec(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrateDomains rebuilds the url table so that aliases are unique per domain.
+// SQLite cannot alter a UNIQUE constraint in place.
+func migrateDomains(defaultDomain string) migration {
+	return func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+		CREATE TABLE url_new(
+			id INTEGER PRIMARY KEY,
+			domain TEXT NOT NULL,
+			alias TEXT NOT NULL,
+			url TEXT NOT NULL,
+			UNIQUE(domain, alias)
+		)`); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			"INSERT INTO url_new(id, domain, alias, url) SELECT id, ?, alias, url FROM url",
+			defaultDomain,
+		); err != nil {
+			return err
+		}
+
+		return execMigration(
+			"DROP TABLE url",
+			"ALTER TABLE url_new RENAME TO url",
+		)(tx)
+	}
+}
@@ -0,0 +1,313 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"url-shortener/internal/storage"
+)
+
+// Full-text search uses an FTS5 table, url_fts, kept in sync with url and
+// url_tag by triggers. go-sqlite3 only includes FTS5 when built with
+// -tags sqlite_fts5; without it SearchURLs falls back to LIKE matching,
+// which is unranked and scans the whole table.
+
+// searchTriggers keep url_fts in sync. setupSearch recreates them all when
+// one is missing.
+var searchTriggers = []struct{ name, sql string }{
+	{"url_fts_insert", `
+	CREATE TRIGGER url_fts_insert AFTER INSERT ON url BEGIN
+		INSERT INTO url_fts(rowid, alias, target, title, notes, tags)
+		VALUES (new.id, new.alias, new.url, new.title, new.notes, '');
+	END`},
+	{"url_fts_update", `
+	CREATE TRIGGER url_fts_update AFTER UPDATE OF alias, url, title, notes ON url BEGIN
+		UPDATE url_fts SET alias = new.alias, target = new.url, title = new.title, notes = new.notes
+		WHERE rowid = new.id;
+	END`},
+	{"url_fts_delete", `
+	CREATE TRIGGER url_fts_delete AFTER DELETE ON url BEGIN
+		DELETE FROM url_fts WHERE rowid = old.id;
+	END`},
+	{"url_tag_fts_insert", `
+	CREATE TRIGGER url_tag_fts_insert AFTER INSERT ON url_tag BEGIN
+		UPDATE url_fts SET tags = ` + tagList("new.url_id") + ` WHERE rowid = new.url_id;
+	END`},
+	{"url_tag_fts_delete", `
+	CREATE TRIGGER url_tag_fts_delete AFTER DELETE ON url_tag BEGIN
+		UPDATE url_fts SET tags = ` + tagList("old.url_id") + ` WHERE rowid = old.url_id;
+	END`},
+}
+
+// tagList is an SQL expression for the space separated tags of a link.
+func tagList(urlID string) string {
+	return `COALESCE((
+		SELECT group_concat(tag.name, ' ') FROM url_tag JOIN tag ON tag.id = url_tag.tag_id
+		WHERE url_tag.url_id = ` + urlID + `
+	), '')`
+}
+
+// FullTextSearch reports whether SearchURLs uses FTS5.
+func (s *Storage) FullTextSearch() bool {
+	return s.fts
+}
+
+// setupSearch creates the search index if SQLite supports FTS5. It is not
+// a migration since the same database may be opened by builds with and
+// without FTS5: the index is rebuilt whenever its triggers are missing,
+// and triggers are dropped by builds that could not run them.
+func (s *Storage) setupSearch() error {
+	const op = "storage.sqlite.setupSearch"
+
+	if err := s.db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&s.fts); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if s.fts {
+		err = createSearchIndex(tx)
+	} else {
+		err = dropSearchTriggers(tx)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+func createSearchIndex(tx *sql.Tx) error {
+	names := make([]any, len(searchTriggers))
+	for i, t := range searchTriggers {
+		names[i] = t.name
+	}
+
+	var n int
+	if err := tx.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN "+placeholders(len(names)),
+		names...,
+	).Scan(&n); err != nil {
+		return err
+	}
+	if n == len(searchTriggers) {
+		return nil
+	}
+
+	if err := execMigration(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS url_fts USING fts5(
+			alias, target, title, notes, tags,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)`,
+		"DELETE FROM url_fts",
+		`INSERT INTO url_fts(rowid, alias, target, title, notes, tags)
+		SELECT id, alias, url, title, notes, `+tagList("url.id")+` FROM url`,
+	)(tx); err != nil {
+		return fmt.Errorf("build index: %w", err)
+	}
+
+	if err := dropSearchTriggers(tx); err != nil {
+		return err
+	}
+	for _, t := range searchTriggers {
+		if _, err := tx.Exec(t.sql); err != nil {
+			return fmt.Errorf("create trigger %s: %w", t.name, err)
+		}
+	}
+
+	return nil
+}
+
+func dropSearchTriggers(tx *sql.Tx) error {
+	for _, t := range searchTriggers {
+		if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + t.name); err != nil {
+			return fmt.Errorf("drop trigger %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// SearchURLs finds links whose alias, target, title, notes or tags contain
+// all words of query; the last word may be incomplete. It returns a page
+// of results, best first, and the total number of matches.
+func (s *Storage) SearchURLs(query string, limit int, offset int) ([]storage.SearchResult, int, error) {
+	const op = "storage.sqlite.SearchURLs"
+
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return []storage.SearchResult{}, 0, nil
+	}
+
+	var (
+		results []storage.SearchResult
+		total   int
+		err     error
+	)
+	if s.fts {
+		results, total, err = s.searchFTS(terms, limit, offset)
+	} else {
+		results, total, err = s.searchLike(terms, limit, offset)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, total, nil
+}
+
+func (s *Storage) searchFTS(terms []string, limit int, offset int) ([]storage.SearchResult, int, error) {
+	// Quoting every word keeps FTS5 operators in the input from being
+	// interpreted, so that no query is a syntax error.
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	quoted[len(quoted)-1] += "*"
+	match := strings.Join(quoted, " ")
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM url_fts WHERE url_fts MATCH ?", match).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	start, end := storage.HighlightStart, storage.HighlightEnd
+	rows, err := s.db.Query(`
+	SELECT `+urlColumns+`, m.h_alias, m.h_target, m.h_title, m.h_notes, m.h_tags, m.rank
+	FROM url JOIN (
+		SELECT rowid AS fts_id,
+			highlight(url_fts, 0, ?, ?) AS h_alias,
+			highlight(url_fts, 1, ?, ?) AS h_target,
+			highlight(url_fts, 2, ?, ?) AS h_title,
+			snippet(url_fts, 3, ?, ?, '…', 24) AS h_notes,
+			highlight(url_fts, 4, ?, ?) AS h_tags,
+			bm25(url_fts, 10.0, 2.0, 5.0, 1.0, 3.0) AS rank
+		FROM url_fts WHERE url_fts MATCH ?
+		ORDER BY rank LIMIT ? OFFSET ?
+	) m ON url.id = m.fts_id
+	ORDER BY m.rank`,
+		start, end, start, end, start, end, start, end, start, end,
+		match, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []storage.SearchResult{}
+	for rows.Next() {
+		var r storage.SearchResult
+		u, err := scanURL(scanFunc(func(dest ...any) error {
+			return rows.Scan(append(dest, &r.Alias, &r.Target, &r.Title, &r.Notes, &r.Tags, &r.Rank)...)
+		}))
+		if err != nil {
+			return nil, 0, fmt.Errorf("scan: %w", err)
+		}
+		r.URL = u
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.loadSearchLabels(results); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+func (s *Storage) searchLike(terms []string, limit int, offset int) ([]storage.SearchResult, int, error) {
+	var (
+		where []string
+		args  []any
+	)
+	for _, t := range terms {
+		pattern := "%" + likeEscaper.Replace(t) + "%"
+		where = append(where, `(
+			alias LIKE ? ESCAPE '\' OR url LIKE ? ESCAPE '\' OR title LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\'
+			OR EXISTS (
+				SELECT 1 FROM url_tag JOIN tag ON tag.id = url_tag.tag_id
+				WHERE url_tag.url_id = url.id AND tag.name LIKE ? ESCAPE '\'
+			)
+		)`)
+		args = append(args, pattern, pattern, pattern, pattern, pattern)
+	}
+	cond := strings.Join(where, " AND ")
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM url WHERE "+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	urls, err := s.queryURLs(
+		"query",
+		"SELECT "+urlColumns+" FROM url WHERE "+cond+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.loadLabels(urls); err != nil {
+		return nil, 0, err
+	}
+
+	quotedTerms := make([]string, len(terms))
+	for i, t := range terms {
+		quotedTerms[i] = regexp.QuoteMeta(t)
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(quotedTerms, "|"))
+	mark := func(s string) string {
+		return re.ReplaceAllString(s, storage.HighlightStart+"$0"+storage.HighlightEnd)
+	}
+
+	results := make([]storage.SearchResult, 0, len(urls))
+	for _, u := range urls {
+		results = append(results, storage.SearchResult{
+			URL:    u,
+			Alias:  mark(u.Alias),
+			Target: mark(u.URL),
+			Title:  mark(u.Title),
+			Notes:  mark(u.Notes),
+			Tags:   mark(strings.Join(u.Tags, " ")),
+		})
+	}
+
+	return results, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *Storage) loadSearchLabels(results []storage.SearchResult) error {
+	urls := make([]storage.URL, len(results))
+	for i, r := range results {
+		urls[i] = r.URL
+	}
+	if err := s.loadLabels(urls); err != nil {
+		return err
+	}
+	for i := range results {
+		results[i].URL = urls[i]
+	}
+	return nil
+}
+
+// scanFunc adapts a function to the scanner interface.
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
+
+func placeholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?,", n), ",") + ")"
+}
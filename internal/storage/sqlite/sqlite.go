@@ -2,8 +2,10 @@ package sqlite
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 	"url-shortener/internal/storage"
 
 	"github.com/mattn/go-sqlite3"
@@ -11,45 +13,70 @@ import (
 
 type Storage struct {
 	db *sql.DB
+	// fts is set when SQLite was built with FTS5, see setupSearch.
+	fts bool
 }
 
-func New(dbPath string) (*Storage, error) {
+// New opens the database at dbPath and applies pending migrations.
+// Links created before multi-domain support are assigned to defaultDomain.
+func New(dbPath string, defaultDomain string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-	CREATE TABLE IF NOT EXISTS url(
-		id INTEGER PRIMARY KEY,
-		alias TEXT NOT NULL UNIQUE,
-		url TEXT NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-	`)
-	if err != nil {
+	s := &Storage{db: db}
+
+	if err := s.migrate(defaultDomain); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
-	if err != nil {
+	if err := s.setupSearch(); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return s, nil
 }
 
-func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
+func (s *Storage) SaveURL(u storage.URL) (int64, error) {
 	const op = "storage.sqlite.SaveURL"
 
-	stmt, err := s.db.Prepare("INSERT INTO url(alias, url) VALUES (?, ?)")
+	rules, err := encodeRules(u.Rules)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	result, err := stmt.Exec(alias, urlToSave)
+	split, err := encodeSplit(u.Split)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schedule, err := encodeSchedule(u.Schedule)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(`
+	INSERT INTO url(domain, alias, url, redirect_type, forward_query, query_precedence, forward_path,
+		title, created_at, password_hash, rules, split, not_before, schedule, notes, owner_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.Domain, u.Alias, u.URL, u.RedirectType,
+		u.ForwardQuery, u.QueryPrecedence, u.ForwardPath,
+		u.Title, u.CreatedAt.UTC(), u.PasswordHash, rules, split,
+		nullTime(u.NotBefore), schedule, u.Notes, u.OwnerID,
+	)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLAlreadyExists)
@@ -63,43 +90,376 @@ func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := addTags(tx, id, u.Tags); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := saveMetadata(tx, id, u.Metadata); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+func (s *Storage) GetURL(domain string, alias string) (storage.URL, error) {
 	const op = "storage.sqlite.GetURL"
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	stmt, err := s.db.Prepare("SELECT " + urlColumns + " FROM url WHERE domain = ? AND alias = ?")
 	if err != nil {
-		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
+		return storage.URL{}, fmt.Errorf("%s: prepare statement: %w", op, err)
 	}
 
-	var resURL string
-
-	err = stmt.QueryRow(alias).Scan(&resURL)
+	u, err := scanURL(stmt.QueryRow(domain, alias))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", storage.ErrURLNotFound
+			return storage.URL{}, storage.ErrURLNotFound
 		}
 
-		return "", fmt.Errorf("%s: ошибка при получении URL: %w", op, err)
+		return storage.URL{}, fmt.Errorf("%s: ошибка при получении URL: %w", op, err)
 	}
 
-	return resURL, nil
+	urls := []storage.URL{u}
+	if err := s.loadLabels(urls); err != nil {
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls[0], nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+// urlColumns lists the url table columns in the order scanURL reads them.
+const urlColumns = `id, domain, alias, url, redirect_type, forward_query, query_precedence, forward_path,
+	title, created_at, password_hash, last_status, last_check_error, last_checked_at,
+	meta_title, meta_description, meta_image, meta_favicon, meta_fetched_at, rules, split,
+	not_before, schedule, notes, owner_id`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanURL(row scanner) (storage.URL, error) {
+	var (
+		u             storage.URL
+		createdAt     sql.NullTime
+		lastCheckedAt sql.NullTime
+		metaFetchedAt sql.NullTime
+		rules         string
+		split         string
+		notBefore     sql.NullTime
+		schedule      string
+	)
+
+	if err := row.Scan(
+		&u.ID, &u.Domain, &u.Alias, &u.URL, &u.RedirectType,
+		&u.ForwardQuery, &u.QueryPrecedence, &u.ForwardPath,
+		&u.Title, &createdAt, &u.PasswordHash,
+		&u.LastStatus, &u.LastCheckError, &lastCheckedAt,
+		&u.Meta.Title, &u.Meta.Description, &u.Meta.Image, &u.Meta.Favicon, &metaFetchedAt,
+		&rules, &split, &notBefore, &schedule, &u.Notes, &u.OwnerID,
+	); err != nil {
+		return storage.URL{}, err
+	}
+	if rules != "" {
+		if err := json.Unmarshal([]byte(rules), &u.Rules); err != nil {
+			return storage.URL{}, fmt.Errorf("decode rules: %w", err)
+		}
+	}
+	if split != "" {
+		if err := json.Unmarshal([]byte(split), &u.Split); err != nil {
+			return storage.URL{}, fmt.Errorf("decode split: %w", err)
+		}
+	}
+	u.NotBefore = notBefore.Time
+	if schedule != "" {
+		if err := json.Unmarshal([]byte(schedule), &u.Schedule); err != nil {
+			return storage.URL{}, fmt.Errorf("decode schedule: %w", err)
+		}
+	}
+	u.CreatedAt = createdAt.Time
+	u.LastCheckedAt = lastCheckedAt.Time
+	u.Meta.FetchedAt = metaFetchedAt.Time
+
+	return u, nil
+}
+
+func (s *Storage) DeleteURL(domain string, alias string) error {
 	const op = "storage.sqlite.DeleteURL"
 
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	stmt, err := s.db.Prepare("DELETE FROM url WHERE domain = ? AND alias = ?")
 	if err != nil {
 		return fmt.Errorf("%s: prepare statement: %w", op, err)
 	}
 
-	_, err = stmt.Exec(alias)
+	_, err = stmt.Exec(domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RecordClick(click storage.Click) error {
+	const op = "storage.sqlite.RecordClick"
+
+	stmt, err := s.db.Prepare("INSERT INTO click(url_id, variant, created_at) VALUES (?, ?, ?)")
 	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+
+	if click.CreatedAt.IsZero() {
+		click.CreatedAt = time.Now()
+	}
+
+	if _, err := stmt.Exec(click.URLID, click.Variant, click.CreatedAt.UTC()); err != nil {
 		return fmt.Errorf("%s: execute statement: %w", op, err)
 	}
 
 	return nil
 }
+
+func (s *Storage) CountClicks(urlID int64) (int64, error) {
+	const op = "storage.sqlite.CountClicks"
+
+	var count int64
+
+	err := s.db.QueryRow("SELECT COUNT(*) FROM click WHERE url_id = ?", urlID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// URLsToCheck returns links never checked or last checked before
+// checkedBefore, least recently checked first.
+func (s *Storage) URLsToCheck(checkedBefore time.Time, limit int) ([]storage.URL, error) {
+	const op = "storage.sqlite.URLsToCheck"
+
+	return s.queryURLs(op, `
+	SELECT `+urlColumns+` FROM url
+	WHERE last_checked_at IS NULL OR last_checked_at < ?
+	ORDER BY last_checked_at IS NOT NULL, last_checked_at
+	LIMIT ?`, checkedBefore.UTC(), limit)
+}
+
+func (s *Storage) SaveCheckResult(urlID int64, status int, checkErr string, checkedAt time.Time) error {
+	const op = "storage.sqlite.SaveCheckResult"
+
+	_, err := s.db.Exec(
+		"UPDATE url SET last_status = ?, last_check_error = ?, last_checked_at = ? WHERE id = ?",
+		status, checkErr, checkedAt.UTC(), urlID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveMetadata(urlID int64, meta storage.PageMeta) error {
+	const op = "storage.sqlite.SaveMetadata"
+
+	_, err := s.db.Exec(`
+	UPDATE url SET meta_title = ?, meta_description = ?, meta_image = ?, meta_favicon = ?, meta_fetched_at = ?
+	WHERE id = ?`,
+		meta.Title, meta.Description, meta.Image, meta.Favicon, meta.FetchedAt.UTC(), urlID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetRules replaces the routing rules of a link.
+func (s *Storage) SetRules(domain string, alias string, rules []storage.Rule) error {
+	const op = "storage.sqlite.SetRules"
+
+	encoded, err := encodeRules(rules)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.Exec("UPDATE url SET rules = ? WHERE domain = ? AND alias = ?", encoded, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// encodeRules stores links without rules as an empty string.
+func encodeRules(rules []storage.Rule) (string, error) {
+	if len(rules) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// SetSplit replaces the A/B split of a link.
+func (s *Storage) SetSplit(domain string, alias string, split storage.Split) error {
+	const op = "storage.sqlite.SetSplit"
+
+	encoded, err := encodeSplit(split)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.Exec("UPDATE url SET split = ? WHERE domain = ? AND alias = ?", encoded, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// CountClicksByVariant returns the number of clicks per split variant.
+// Clicks served without a split are counted under "".
+func (s *Storage) CountClicksByVariant(urlID int64) (map[string]int64, error) {
+	const op = "storage.sqlite.CountClicksByVariant"
+
+	rows, err := s.db.Query("SELECT variant, COUNT(*) FROM click WHERE url_id = ? GROUP BY variant", urlID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var (
+			variant string
+			n       int64
+		)
+		if err := rows.Scan(&variant, &n); err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		counts[variant] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return counts, nil
+}
+
+// SetSchedule replaces the activation time and target schedule of a link.
+func (s *Storage) SetSchedule(
+	domain string,
+	alias string,
+	notBefore time.Time,
+	entries []storage.ScheduleEntry,
+) error {
+	const op = "storage.sqlite.SetSchedule"
+
+	encoded, err := encodeSchedule(entries)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.db.Exec(
+		"UPDATE url SET not_before = ?, schedule = ? WHERE domain = ? AND alias = ?",
+		nullTime(notBefore), encoded, domain, alias,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+func encodeSchedule(entries []storage.ScheduleEntry) (string, error) {
+	if len(entries) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func encodeSplit(split storage.Split) (string, error) {
+	if len(split.Variants) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(split)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// BrokenURLs returns links whose last health check failed or returned
+// an error status, most recently checked first.
+func (s *Storage) BrokenURLs(limit int) ([]storage.URL, error) {
+	const op = "storage.sqlite.BrokenURLs"
+
+	return s.queryURLs(op, `
+	SELECT `+urlColumns+` FROM url
+	WHERE last_checked_at IS NOT NULL AND (last_status = 0 OR last_status >= 400)
+	ORDER BY last_checked_at DESC
+	LIMIT ?`, limit)
+}
+
+func (s *Storage) queryURLs(op string, query string, args ...any) ([]storage.URL, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var urls []storage.URL
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,410 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/storage"
+)
+
+func TestNew_MigratesLegacySchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+	CREATE TABLE url(
+		id INTEGER PRIMARY KEY,
+		alias TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL
+	);
+	INSERT INTO url(alias, url) VALUES ('docs', 'https://example.com/docs');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := New(dbPath, "go.example")
+	require.NoError(t, err)
+
+	got, err := s.GetURL("go.example", "docs")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/docs", got.URL)
+	require.Equal(t, http.StatusFound, got.RedirectType)
+
+	other := storage.URL{
+		Domain:       "s.example",
+		Alias:        "docs",
+		URL:          "https://example.com/other",
+		RedirectType: http.StatusFound,
+	}
+	_, err = s.SaveURL(other)
+	require.NoError(t, err)
+
+	other.Domain = "go.example"
+	_, err = s.SaveURL(other)
+	require.ErrorIs(t, err, storage.ErrURLAlreadyExists)
+
+	// Reopening must not run the migrations again.
+	_, err = New(dbPath, "go.example")
+	require.NoError(t, err)
+}
+
+func TestStorage_CheckResults(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var ids []int64
+	for _, alias := range []string{"ok", "gone", "fresh"} {
+		id, err := s.SaveURL(storage.URL{Domain: "go.example", Alias: alias, URL: "https://example.com/" + alias})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	due, err := s.URLsToCheck(now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 3)
+
+	require.NoError(t, s.SaveCheckResult(ids[0], http.StatusOK, "", now.Add(-2*time.Hour)))
+	require.NoError(t, s.SaveCheckResult(ids[1], http.StatusNotFound, "", now.Add(-3*time.Hour)))
+	require.NoError(t, s.SaveCheckResult(ids[2], 0, "connection refused", now))
+
+	due, err = s.URLsToCheck(now.Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	require.Equal(t, "gone", due[0].Alias)
+	require.Equal(t, "ok", due[1].Alias)
+
+	broken, err := s.BrokenURLs(10)
+	require.NoError(t, err)
+	require.Len(t, broken, 2)
+	require.Equal(t, "fresh", broken[0].Alias)
+	require.Equal(t, "connection refused", broken[0].LastCheckError)
+	require.Equal(t, "gone", broken[1].Alias)
+	require.Equal(t, http.StatusNotFound, broken[1].LastStatus)
+	require.True(t, broken[1].LastCheckedAt.Equal(now.Add(-3*time.Hour)))
+}
+
+func TestStorage_SaveMetadata(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	id, err := s.SaveURL(storage.URL{Domain: "go.example", Alias: "docs", URL: "https://example.com/docs"})
+	require.NoError(t, err)
+
+	meta := storage.PageMeta{
+		Title:       "Docs",
+		Description: "All the docs",
+		Image:       "https://example.com/og.png",
+		Favicon:     "https://example.com/favicon.ico",
+		FetchedAt:   time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, s.SaveMetadata(id, meta))
+
+	got, err := s.GetURL("go.example", "docs")
+	require.NoError(t, err)
+	require.True(t, meta.FetchedAt.Equal(got.Meta.FetchedAt))
+	got.Meta.FetchedAt = meta.FetchedAt
+	require.Equal(t, meta, got.Meta)
+}
+
+func TestStorage_SetRules(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	rules := []storage.Rule{
+		{Platform: "ios", Target: "https://apps.example.com/app"},
+		{Languages: []string{"de"}, Headers: map[string]string{"X-Beta": "*"}, Target: "https://example.com/de"},
+	}
+
+	_, err = s.SaveURL(storage.URL{Domain: "go.example", Alias: "app", URL: "https://example.com", Rules: rules[:1]})
+	require.NoError(t, err)
+
+	got, err := s.GetURL("go.example", "app")
+	require.NoError(t, err)
+	require.Equal(t, rules[:1], got.Rules)
+
+	require.NoError(t, s.SetRules("go.example", "app", rules))
+	got, err = s.GetURL("go.example", "app")
+	require.NoError(t, err)
+	require.Equal(t, rules, got.Rules)
+
+	require.NoError(t, s.SetRules("go.example", "app", nil))
+	got, err = s.GetURL("go.example", "app")
+	require.NoError(t, err)
+	require.Empty(t, got.Rules)
+
+	require.ErrorIs(t, s.SetRules("go.example", "missing", rules), storage.ErrURLNotFound)
+}
+
+func TestStorage_Split(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	split := storage.Split{
+		Sticky: true,
+		Variants: []storage.Variant{
+			{Name: "a", Target: "https://example.com/a", Weight: 70},
+			{Name: "b", Target: "https://example.com/b", Weight: 30},
+		},
+	}
+
+	id, err := s.SaveURL(storage.URL{Domain: "go.example", Alias: "landing", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetSplit("go.example", "landing", split))
+	got, err := s.GetURL("go.example", "landing")
+	require.NoError(t, err)
+	require.Equal(t, split, got.Split)
+
+	for _, variant := range []string{"a", "a", "b", ""} {
+		require.NoError(t, s.RecordClick(storage.Click{URLID: id, Variant: variant}))
+	}
+
+	counts, err := s.CountClicksByVariant(id)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"a": 2, "b": 1, "": 1}, counts)
+
+	require.ErrorIs(t, s.SetSplit("go.example", "missing", split), storage.ErrURLNotFound)
+}
+
+func TestStorage_SetSchedule(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	notBefore := time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC)
+	entries := []storage.ScheduleEntry{
+		{At: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), Target: "https://example.com/product"},
+	}
+
+	_, err = s.SaveURL(storage.URL{Domain: "go.example", Alias: "launch", URL: "https://example.com", NotBefore: notBefore})
+	require.NoError(t, err)
+
+	got, err := s.GetURL("go.example", "launch")
+	require.NoError(t, err)
+	require.True(t, notBefore.Equal(got.NotBefore))
+	require.Empty(t, got.Schedule)
+
+	require.NoError(t, s.SetSchedule("go.example", "launch", time.Time{}, entries))
+	got, err = s.GetURL("go.example", "launch")
+	require.NoError(t, err)
+	require.True(t, got.NotBefore.IsZero())
+	require.Equal(t, entries, got.Schedule)
+
+	require.ErrorIs(t, s.SetSchedule("go.example", "missing", notBefore, entries), storage.ErrURLNotFound)
+}
+
+func TestStorage_Tags(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(storage.URL{
+		Domain:   "go.example",
+		Alias:    "docs",
+		URL:      "https://example.com/docs",
+		Tags:     []string{"team:growth", "docs"},
+		Metadata: map[string]json.RawMessage{"owner": json.RawMessage(`{"team":"growth"}`)},
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL(storage.URL{Domain: "go.example", Alias: "blog", URL: "https://example.com/blog"})
+	require.NoError(t, err)
+
+	got, err := s.GetURL("go.example", "docs")
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs", "team:growth"}, got.Tags)
+	require.JSONEq(t, `{"team":"growth"}`, string(got.Metadata["owner"]))
+
+	require.NoError(t, s.AddTags("go.example", "blog", []string{"docs", "blog"}))
+	require.ErrorIs(t, s.AddTags("go.example", "missing", []string{"docs"}), storage.ErrURLNotFound)
+
+	tags, err := s.ListTags()
+	require.NoError(t, err)
+	require.Equal(t, []storage.TagCount{
+		{Name: "blog", Count: 1},
+		{Name: "docs", Count: 2},
+		{Name: "team:growth", Count: 1},
+	}, tags)
+
+	urls, err := s.ListURLs(storage.ListFilter{Tag: "docs", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	require.Equal(t, "blog", urls[0].Alias)
+	require.Equal(t, []string{"blog", "docs"}, urls[0].Tags)
+	require.Equal(t, "docs", urls[1].Alias)
+	require.Len(t, urls[1].Metadata, 1)
+
+	urls, err = s.ListURLs(storage.ListFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, "docs", urls[0].Alias)
+
+	require.NoError(t, s.RemoveTag("go.example", "blog", "blog"))
+	require.NoError(t, s.RemoveTag("go.example", "docs", "team:growth"))
+	tags, err = s.ListTags()
+	require.NoError(t, err)
+	require.Equal(t, []storage.TagCount{{Name: "docs", Count: 2}}, tags)
+
+	require.NoError(t, s.DeleteURL("go.example", "docs"))
+	tags, err = s.ListTags()
+	require.NoError(t, err)
+	require.Equal(t, []storage.TagCount{{Name: "docs", Count: 1}}, tags)
+}
+
+// TestStorage_SearchURLs runs against FTS5 with -tags sqlite_fts5 and
+// against the LIKE fallback without.
+func TestStorage_SearchURLs(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	for _, u := range []storage.URL{
+		{Alias: "q3", URL: "https://reports.example.com/2026/q3.pdf", Title: "Quarterly report", Notes: "Board meeting"},
+		{Alias: "blog", URL: "https://example.com/blog", Title: "Company blog"},
+		{Alias: "hiring", URL: "https://example.com/jobs", Notes: "For the report_2026 campaign"},
+	} {
+		u.Domain = "go.example"
+		_, err := s.SaveURL(u)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.AddTags("go.example", "blog", []string{"finance"}))
+
+	aliases := func(results []storage.SearchResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.URL.Alias)
+		}
+		return out
+	}
+
+	results, total, err := s.SearchURLs("Quarterly REP", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, []string{"q3"}, aliases(results))
+	require.Contains(t, results[0].Title, storage.HighlightStart+"Quarterly"+storage.HighlightEnd)
+
+	results, total, err = s.SearchURLs("finance", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, []string{"blog"}, aliases(results))
+	require.Equal(t, []string{"finance"}, results[0].URL.Tags)
+	require.Equal(t, storage.HighlightStart+"finance"+storage.HighlightEnd, results[0].Tags)
+
+	results, total, err = s.SearchURLs("report", 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Len(t, results, 1)
+	if s.FullTextSearch() {
+		// The title match outranks the one in the notes.
+		require.Equal(t, []string{"q3"}, aliases(results))
+	}
+
+	// Syntax of the FTS5 query language is searched for literally.
+	_, _, err = s.SearchURLs(`"report AND (`, 10, 0)
+	require.NoError(t, err)
+
+	// Triggers keep the index up to date.
+	require.NoError(t, s.RemoveTag("go.example", "blog", "finance"))
+	require.NoError(t, s.DeleteURL("go.example", "q3"))
+	_, total, err = s.SearchURLs("finance", 10, 0)
+	require.NoError(t, err)
+	require.Zero(t, total)
+	_, total, err = s.SearchURLs("quarterly", 10, 0)
+	require.NoError(t, err)
+	require.Zero(t, total)
+}
+
+func TestStorage_APIKeys(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	id, err := s.SaveAPIKey(storage.APIKey{
+		Name:      "ci",
+		Prefix:    "usk_abcdefgh",
+		Hash:      "hash",
+		Scopes:    []string{"links:write", "links:read"},
+		OwnerID:   7,
+		CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+
+	k, err := s.APIKeyByHash("hash")
+	require.NoError(t, err)
+	require.Equal(t, id, k.ID)
+	require.Equal(t, []string{"links:write", "links:read"}, k.Scopes)
+	require.Equal(t, int64(7), k.OwnerID)
+	require.True(t, createdAt.Equal(k.CreatedAt))
+	require.True(t, k.LastUsedAt.IsZero())
+
+	_, err = s.APIKeyByHash("other")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	// Uses within a minute of the recorded one are skipped.
+	used := createdAt.Add(time.Hour)
+	require.NoError(t, s.TouchAPIKey(id, used))
+	require.NoError(t, s.TouchAPIKey(id, used.Add(30*time.Second)))
+	k, err = s.APIKeyByHash("hash")
+	require.NoError(t, err)
+	require.True(t, used.Equal(k.LastUsedAt))
+
+	require.NoError(t, s.TouchAPIKey(id, used.Add(2*time.Minute)))
+	k, err = s.APIKeyByHash("hash")
+	require.NoError(t, err)
+	require.True(t, used.Add(2*time.Minute).Equal(k.LastUsedAt))
+
+	revokedAt := createdAt.Add(2 * time.Hour)
+	require.NoError(t, s.RevokeAPIKey(id, revokedAt))
+	require.NoError(t, s.RevokeAPIKey(id, revokedAt.Add(time.Hour)))
+	require.ErrorIs(t, s.RevokeAPIKey(id+1, revokedAt), storage.ErrAPIKeyNotFound)
+
+	keys, err := s.ListAPIKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.True(t, revokedAt.Equal(keys[0].RevokedAt))
+}
+
+func TestStorage_TokenRevocations(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"), "go.example")
+	require.NoError(t, err)
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.RevokeToken(storage.RevokedToken{
+		JTI: "expired", UserID: 7, RevokedBy: 1, RevokedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour),
+	}))
+	require.NoError(t, s.RevokeToken(storage.RevokedToken{
+		JTI: "live", UserID: 7, RevokedBy: 1, RevokedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour),
+	}))
+	require.NoError(t, s.RevokeToken(storage.RevokedToken{JTI: "forever", RevokedBy: 1, RevokedAt: now}))
+	// Revoking again keeps the first record.
+	require.NoError(t, s.RevokeToken(storage.RevokedToken{JTI: "live", RevokedBy: 2, RevokedAt: now}))
+
+	tokens, err := s.RevokedTokens(now)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, "forever", tokens[0].JTI)
+	require.True(t, tokens[0].ExpiresAt.IsZero())
+	require.Equal(t, "live", tokens[1].JTI)
+	require.Equal(t, int64(1), tokens[1].RevokedBy)
+	require.True(t, now.Add(time.Hour).Equal(tokens[1].ExpiresAt))
+
+	n, err := s.DeleteExpiredRevokedTokens(now)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	require.NoError(t, s.SetTokenCutoff(storage.TokenCutoff{UserID: 7, IssuedBefore: now, SetBy: 1, SetAt: now}))
+	require.NoError(t, s.SetTokenCutoff(storage.TokenCutoff{
+		UserID: 7, IssuedBefore: now.Add(time.Hour), SetBy: 2, SetAt: now.Add(time.Hour),
+	}))
+
+	cutoffs, err := s.TokenCutoffs()
+	require.NoError(t, err)
+	require.Len(t, cutoffs, 1)
+	require.True(t, now.Add(time.Hour).Equal(cutoffs[0].IssuedBefore))
+	require.Equal(t, int64(2), cutoffs[0].SetBy)
+}
@@ -0,0 +1,233 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"url-shortener/internal/storage"
+)
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// AddTags adds tags to a link. Tags it already has are left alone.
+func (s *Storage) AddTags(domain string, alias string, tags []string) error {
+	const op = "storage.sqlite.AddTags"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := urlID(tx, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := addTags(tx, id, tags); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// RemoveTag removes a tag from a link. Tags no link uses any more are
+// deleted, so that they disappear from ListTags.
+func (s *Storage) RemoveTag(domain string, alias string, tag string) error {
+	const op = "storage.sqlite.RemoveTag"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: begin: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := urlID(tx, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(
+		"DELETE FROM url_tag WHERE url_id = ? AND tag_id = (SELECT id FROM tag WHERE name = ?)",
+		id, tag,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(
+		"DELETE FROM tag WHERE name = ? AND NOT EXISTS (SELECT 1 FROM url_tag WHERE tag_id = tag.id)",
+		tag,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListTags returns all tags in use with the number of links per tag.
+func (s *Storage) ListTags() ([]storage.TagCount, error) {
+	const op = "storage.sqlite.ListTags"
+
+	rows, err := s.db.Query(`
+	SELECT tag.name, COUNT(url_tag.url_id) FROM tag
+	JOIN url_tag ON url_tag.tag_id = tag.id
+	GROUP BY tag.id
+	ORDER BY tag.name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := []storage.TagCount{}
+	for rows.Next() {
+		var t storage.TagCount
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tags, nil
+}
+
+// ListURLs returns links with their tags and metadata, newest first.
+func (s *Storage) ListURLs(filter storage.ListFilter) ([]storage.URL, error) {
+	const op = "storage.sqlite.ListURLs"
+
+	query := "SELECT " + urlColumns + " FROM url"
+	var args []any
+	if filter.Tag != "" {
+		query += `
+	WHERE id IN (
+		SELECT url_tag.url_id FROM url_tag JOIN tag ON tag.id = url_tag.tag_id WHERE tag.name = ?
+	)`
+		args = append(args, filter.Tag)
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	urls, err := s.queryURLs(op, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadLabels(urls); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+func urlID(tx *sql.Tx, domain string, alias string) (int64, error) {
+	var id int64
+	err := tx.QueryRow("SELECT id FROM url WHERE domain = ? AND alias = ?", domain, alias).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrURLNotFound
+	}
+	return id, err
+}
+
+func addTags(db execer, urlID int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := db.Exec("INSERT INTO tag(name) VALUES (?) ON CONFLICT(name) DO NOTHING", tag); err != nil {
+			return fmt.Errorf("insert tag: %w", err)
+		}
+		if _, err := db.Exec(
+			"INSERT OR IGNORE INTO url_tag(url_id, tag_id) SELECT ?, id FROM tag WHERE name = ?",
+			urlID, tag,
+		); err != nil {
+			return fmt.Errorf("tag url: %w", err)
+		}
+	}
+	return nil
+}
+
+func saveMetadata(db execer, urlID int64, metadata map[string]json.RawMessage) error {
+	for key, value := range metadata {
+		if _, err := db.Exec(
+			"INSERT INTO url_metadata(url_id, key, value) VALUES (?, ?, ?)",
+			urlID, key, string(value),
+		); err != nil {
+			return fmt.Errorf("insert metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadLabels fills in the tags and metadata of urls.
+func (s *Storage) loadLabels(urls []storage.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(urls))
+	args := make([]any, len(urls))
+	for i, u := range urls {
+		index[u.ID] = i
+		args[i] = u.ID
+	}
+	in := placeholders(len(urls))
+
+	rows, err := s.db.Query(`
+	SELECT url_tag.url_id, tag.name FROM url_tag
+	JOIN tag ON tag.id = url_tag.tag_id
+	WHERE url_tag.url_id IN `+in+`
+	ORDER BY tag.name`, args...)
+	if err != nil {
+		return fmt.Errorf("load tags: %w", err)
+	}
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("load tags: %w", err)
+		}
+		u := &urls[index[id]]
+		u.Tags = append(u.Tags, name)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load tags: %w", err)
+	}
+
+	rows, err = s.db.Query("SELECT url_id, key, value FROM url_metadata WHERE url_id IN "+in, args...)
+	if err != nil {
+		return fmt.Errorf("load metadata: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			id         int64
+			key, value string
+		)
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return fmt.Errorf("load metadata: %w", err)
+		}
+		u := &urls[index[id]]
+		if u.Metadata == nil {
+			u.Metadata = make(map[string]json.RawMessage)
+		}
+		u.Metadata[key] = json.RawMessage(value)
+	}
+
+	return rows.Err()
+}
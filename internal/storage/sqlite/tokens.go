@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// RevokeToken records a revoked JWT. Revoking it again keeps the first
+// record.
+func (s *Storage) RevokeToken(t storage.RevokedToken) error {
+	const op = "storage.sqlite.RevokeToken"
+
+	if t.RevokedAt.IsZero() {
+		t.RevokedAt = time.Now()
+	}
+
+	if _, err := s.db.Exec(
+		`INSERT INTO revoked_token(jti, user_id, revoked_by, revoked_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(jti) DO NOTHING`,
+		t.JTI, t.UserID, t.RevokedBy, t.RevokedAt.UTC(), nullTime(t.ExpiresAt),
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokedTokens returns the revoked JWTs that have not expired at now,
+// most recently revoked first.
+func (s *Storage) RevokedTokens(now time.Time) ([]storage.RevokedToken, error) {
+	const op = "storage.sqlite.RevokedTokens"
+
+	rows, err := s.db.Query(
+		`SELECT jti, user_id, revoked_by, revoked_at, expires_at FROM revoked_token
+		WHERE expires_at IS NULL OR expires_at > ?
+		ORDER BY revoked_at DESC`,
+		now.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tokens := []storage.RevokedToken{}
+	for rows.Next() {
+		var (
+			t         storage.RevokedToken
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&t.JTI, &t.UserID, &t.RevokedBy, &t.RevokedAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		t.ExpiresAt = expiresAt.Time
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// DeleteExpiredRevokedTokens drops the revoked JWTs that expired before
+// now, which are rejected anyway.
+func (s *Storage) DeleteExpiredRevokedTokens(now time.Time) (int64, error) {
+	const op = "storage.sqlite.DeleteExpiredRevokedTokens"
+
+	result, err := s.db.Exec("DELETE FROM revoked_token WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// SetTokenCutoff sets the cutoff of a user, replacing any previous one.
+func (s *Storage) SetTokenCutoff(c storage.TokenCutoff) error {
+	const op = "storage.sqlite.SetTokenCutoff"
+
+	if c.SetAt.IsZero() {
+		c.SetAt = time.Now()
+	}
+
+	if _, err := s.db.Exec(
+		`INSERT INTO token_cutoff(user_id, issued_before, set_by, set_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			issued_before = excluded.issued_before,
+			set_by = excluded.set_by,
+			set_at = excluded.set_at`,
+		c.UserID, c.IssuedBefore.UTC(), c.SetBy, c.SetAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// TokenCutoffs returns the cutoffs of all users, most recently set first.
+func (s *Storage) TokenCutoffs() ([]storage.TokenCutoff, error) {
+	const op = "storage.sqlite.TokenCutoffs"
+
+	rows, err := s.db.Query("SELECT user_id, issued_before, set_by, set_at FROM token_cutoff ORDER BY set_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	cutoffs := []storage.TokenCutoff{}
+	for rows.Next() {
+		var c storage.TokenCutoff
+		if err := rows.Scan(&c.UserID, &c.IssuedBefore, &c.SetBy, &c.SetAt); err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		cutoffs = append(cutoffs, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cutoffs, nil
+}
@@ -1,8 +1,193 @@
 package storage
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// URL is a short link: alias on a short domain pointing to a target URL.
+type URL struct {
+	ID           int64
+	Domain       string
+	Alias        string
+	URL          string
+	RedirectType int
+	// ForwardQuery merges the incoming query string into the target's one.
+	ForwardQuery bool
+	// QueryPrecedence decides which side wins for keys present in both
+	// queries: QueryPrecedenceTarget or QueryPrecedenceIncoming.
+	QueryPrecedence string
+	// ForwardPath appends the path after the alias to the target's path.
+	ForwardPath bool
+	Title       string
+	// Notes are free text for admins, e.g. what the link is used for.
+	Notes string
+	// CreatedAt is zero for links created before it was tracked.
+	CreatedAt time.Time
+	// OwnerID is the user who created the link; zero for links created
+	// before owners were tracked.
+	OwnerID int64
+	// PasswordHash is a bcrypt hash; empty for links without a password.
+	PasswordHash string
+	// LastStatus is the HTTP status of the last health check of the target,
+	// 0 if it could not be reached. LastCheckedAt is zero if never checked.
+	LastStatus     int
+	LastCheckError string
+	LastCheckedAt  time.Time
+	// Meta is fetched from the target page after the link is saved.
+	Meta PageMeta
+	// Rules are tried in order before falling back to URL.
+	Rules []Rule
+	// Split spreads requests not matched by a rule over several targets.
+	Split Split
+	// NotBefore is when the link goes live; zero means immediately.
+	NotBefore time.Time
+	// Schedule switches the default target over time, see package schedule.
+	Schedule []ScheduleEntry
+	// Tags group links, e.g. by campaign or team. They are lowercase.
+	Tags []string
+	// Metadata is a free-form JSON object set by clients. Unlike Meta it is
+	// never filled in by the service.
+	Metadata map[string]json.RawMessage
+}
+
+// APIKey lets services call the API without a JWT. Only a hash of the
+// key is stored; Prefix is the start of the key, kept to tell keys apart.
+type APIKey struct {
+	ID     int64
+	Name   string
+	Prefix string
+	Hash   string
+	Scopes []string
+	// OwnerID is the admin who created the key. Requests made with the
+	// key act on their behalf.
+	OwnerID   int64
+	CreatedAt time.Time
+	// LastUsedAt and RevokedAt are zero if the key was never used or
+	// revoked.
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+}
+
+// RevokedToken is a JWT rejected before it expires, identified by its
+// jti claim.
+type RevokedToken struct {
+	JTI string
+	// UserID is the user the token was issued to, if known.
+	UserID int64
+	// RevokedBy is the admin who revoked it.
+	RevokedBy int64
+	RevokedAt time.Time
+	// ExpiresAt is when the token expires anyway and the entry may be
+	// dropped; zero keeps it forever.
+	ExpiresAt time.Time
+}
+
+// TokenCutoff rejects the JWTs of a user issued before IssuedBefore, e.g.
+// all tokens of a compromised account.
+type TokenCutoff struct {
+	UserID       int64
+	IssuedBefore time.Time
+	SetBy        int64
+	SetAt        time.Time
+}
+
+// TagCount is a tag with the number of links carrying it.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// ListFilter selects links for listings and exports.
+type ListFilter struct {
+	// Tag, when set, only selects links with this tag.
+	Tag    string
+	Limit  int
+	Offset int
+}
+
+// SearchResult is a link matching a search. The highlighted fields hold
+// the searched fields with matches between HighlightStart and HighlightEnd;
+// Notes may be shortened to the part around the matches.
+type SearchResult struct {
+	URL    URL
+	Alias  string
+	Target string
+	Title  string
+	Notes  string
+	Tags   string
+	// Rank orders results, lower is better. It is 0 without full-text
+	// search support.
+	Rank float64
+}
+
+// Match markers in SearchResult. They are control characters so that they
+// cannot be confused with text and are easy to escape.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// ScheduleEntry makes Target the default target from At on.
+type ScheduleEntry struct {
+	At     time.Time `json:"at"`
+	Target string    `json:"target"`
+}
+
+// Split is an A/B experiment. Links without variants use URL.
+type Split struct {
+	Variants []Variant `json:"variants"`
+	// Sticky keeps serving a client the variant it got first.
+	Sticky bool `json:"sticky,omitempty"`
+}
+
+// Variant receives Weight out of the sum of all weights of requests.
+type Variant struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// Rule sends matching requests to Target. Every condition that is set
+// must match; see package routing for the semantics.
+type Rule struct {
+	Platform  string            `json:"platform,omitempty"`
+	Languages []string          `json:"languages,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Target    string            `json:"target"`
+}
+
+// PageMeta describes the target page. FetchedAt is zero until the page
+// has been fetched successfully.
+type PageMeta struct {
+	Title       string
+	Description string
+	Image       string
+	Favicon     string
+	FetchedAt   time.Time
+}
+
+// IsBroken reports whether the last health check found the target broken.
+func (u URL) IsBroken() bool {
+	return !u.LastCheckedAt.IsZero() && (u.LastStatus == 0 || u.LastStatus >= 400)
+}
+
+// Click is a single followed redirect.
+type Click struct {
+	URLID int64
+	// Variant is the name of the split variant served, if any.
+	Variant   string
+	CreatedAt time.Time
+}
+
+const (
+	QueryPrecedenceTarget   = "target"
+	QueryPrecedenceIncoming = "incoming"
+)
 
 var (
 	ErrURLNotFound      = errors.New("Ссылка не найдена")
 	ErrURLAlreadyExists = errors.New("Ссылка уже существует")
+	ErrAPIKeyNotFound   = errors.New("api key not found")
 )
@@ -2,8 +2,10 @@ package tests
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -17,15 +19,22 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/require"
 
+	"url-shortener/internal/http-server/handlers/url/redirect"
 	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/auth"
 	"url-shortener/internal/http-server/router"
 	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/domains"
 	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/throttle"
+	"url-shortener/internal/lib/urlpolicy"
 	"url-shortener/internal/storage/sqlite"
 )
 
 const (
-	testAppSecret = "dev-secret"
+	testAppSecret     = "dev-secret"
+	testDefaultDomain = "go.example"
+	testOtherDomain   = "s.example"
 )
 
 func TestURLShortener_HappyPath(t *testing.T) {
@@ -62,6 +71,18 @@ func TestURLShortener_SaveRedirectDelete(t *testing.T) {
 			alias: gofakeit.Word(),
 			error: "поле URL должно быть валидным URL",
 		},
+		{
+			name:  "Unsafe scheme",
+			url:   "javascript:alert(1)",
+			alias: gofakeit.Word(),
+			error: `url is not allowed: scheme is not allowed: "javascript"`,
+		},
+		{
+			name:  "Loopback target",
+			url:   "http://127.0.0.1:8080/admin",
+			alias: gofakeit.Word(),
+			error: "url is not allowed: url points to a private network: 127.0.0.1",
+		},
 		{
 			name:  "Empty Alias",
 			url:   gofakeit.URL(),
@@ -119,21 +140,300 @@ func TestURLShortener_SaveRedirectDelete(t *testing.T) {
 	}
 }
 
+func TestURLShortener_HostScopedAliases(t *testing.T) {
+	e, _ := newTestClient(t)
+
+	alias := random.NewRandomString(10)
+	targets := map[string]string{
+		testDefaultDomain: gofakeit.URL(),
+		testOtherDomain:   gofakeit.URL(),
+	}
+
+	for domain, target := range targets {
+		e.POST("/url").
+			WithJSON(save.Request{
+				URL:    target,
+				Alias:  alias,
+				Domain: domain,
+			}).
+			WithHeader("Authorization", "Bearer "+makeHS256JWT(t, testAppSecret, 1, time.Now().Add(10*time.Minute))).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			HasValue("domain", domain)
+	}
+
+	for domain, target := range targets {
+		e.GET("/" + alias).
+			WithHost(domain).
+			WithRedirectPolicy(httpexpect.DontFollowRedirects).
+			Expect().
+			Status(http.StatusFound).
+			Header("Location").IsEqual(target)
+	}
+}
+
+func TestURLShortener_Passthrough(t *testing.T) {
+	e, _ := newTestClient(t)
+
+	alias := random.NewRandomString(10)
+
+	e.POST("/url").
+		WithJSON(save.Request{
+			URL:          "https://example.com/docs?lang=en",
+			Alias:        alias,
+			ForwardQuery: true,
+			ForwardPath:  true,
+		}).
+		WithHeader("Authorization", "Bearer "+makeHS256JWT(t, testAppSecret, 1, time.Now().Add(10*time.Minute))).
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/"+alias+"/files/report.pdf").
+		WithQuery("utm_source", "x").
+		WithQuery("lang", "de").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual("https://example.com/docs/files/report.pdf?lang=en&utm_source=x")
+}
+
+func TestURLShortener_Preview(t *testing.T) {
+	e, baseURL := newTestClient(t)
+
+	alias := random.NewRandomString(10)
+	target := gofakeit.URL()
+
+	e.POST("/url").
+		WithJSON(save.Request{
+			URL:   target,
+			Alias: alias,
+			Title: "Quarterly report",
+		}).
+		WithHeader("Authorization", "Bearer "+makeHS256JWT(t, testAppSecret, 1, time.Now().Add(10*time.Minute))).
+		Expect().
+		Status(http.StatusOK)
+
+	body := e.GET("/" + alias + "+").
+		Expect().
+		Status(http.StatusOK).
+		Body()
+	body.Contains("Quarterly report")
+	body.Contains("<dd>0</dd>")
+
+	testRedirect(t, baseURL, alias, target)
+
+	e.GET("/"+alias).
+		WithQuery("preview", "1").
+		Expect().
+		Status(http.StatusOK).
+		Body().Contains("<dd>1</dd>")
+}
+
+func TestURLShortener_APIKeys(t *testing.T) {
+	e, baseURL := newTestClient(t)
+	bearer := "Bearer " + makeHS256JWT(t, testAppSecret, 1, time.Now().Add(10*time.Minute))
+
+	created := e.POST("/url/keys").
+		WithJSON(map[string]any{"name": "ci", "scopes": []string{"links:write"}}).
+		WithHeader("Authorization", bearer).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	secret := created.Value("secret").String().NotEmpty().Raw()
+	keyID := created.Value("key").Object().Value("id").Number().Raw()
+
+	// The key creates links on behalf of its owner...
+	target := gofakeit.URL()
+	alias := random.NewRandomString(10)
+	e.POST("/url").
+		WithJSON(save.Request{URL: target, Alias: alias}).
+		WithHeader("X-API-Key", secret).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("alias").String().IsEqual(alias)
+	testRedirect(t, baseURL, alias, target)
+
+	// ...but may neither read them nor manage keys.
+	e.GET("/url/"+alias).
+		WithHeader("X-API-Key", secret).
+		Expect().Status(http.StatusForbidden)
+	e.GET("/url/keys").
+		WithHeader("X-API-Key", secret).
+		Expect().Status(http.StatusForbidden)
+
+	keys := e.GET("/url/keys").
+		WithHeader("Authorization", bearer).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("keys").Array()
+	keys.Length().IsEqual(1)
+	keys.Value(0).Object().ContainsKey("last_used_at")
+
+	e.DELETE(fmt.Sprintf("/url/keys/%d", int64(keyID))).
+		WithHeader("Authorization", bearer).
+		Expect().Status(http.StatusOK)
+
+	e.POST("/url").
+		WithJSON(save.Request{URL: gofakeit.URL()}).
+		WithHeader("X-API-Key", secret).
+		Expect().Status(http.StatusUnauthorized)
+	e.POST("/url").
+		WithJSON(save.Request{URL: gofakeit.URL()}).
+		WithHeader("X-API-Key", "usk_unknown").
+		Expect().Status(http.StatusUnauthorized)
+}
+
+func TestURLShortener_Roles(t *testing.T) {
+	e, baseURL := newTestClient(t)
+	admin := "Bearer " + makeHS256JWT(t, testAppSecret, 1, time.Now().Add(10*time.Minute))
+	creator := "Bearer " + makeHS256JWT(t, testAppSecret, 2, time.Now().Add(10*time.Minute))
+	viewer := "Bearer " + makeHS256JWT(t, testAppSecret, 3, time.Now().Add(10*time.Minute))
+
+	adminAlias := random.NewRandomString(10)
+	e.POST("/url").
+		WithJSON(save.Request{URL: gofakeit.URL(), Alias: adminAlias}).
+		WithHeader("Authorization", admin).
+		Expect().Status(http.StatusOK)
+
+	ownAlias := random.NewRandomString(10)
+	e.POST("/url").
+		WithJSON(save.Request{URL: gofakeit.URL(), Alias: ownAlias}).
+		WithHeader("Authorization", creator).
+		Expect().Status(http.StatusOK)
+	e.GET("/url/"+ownAlias).
+		WithHeader("Authorization", creator).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("link").Object().HasValue("owner_id", 2)
+
+	// Creators manage their own links only.
+	e.DELETE("/url/"+adminAlias).
+		WithHeader("Authorization", creator).
+		Expect().Status(http.StatusForbidden)
+	e.GET("/url/keys").
+		WithHeader("Authorization", creator).
+		Expect().Status(http.StatusForbidden)
+	e.DELETE("/url/"+ownAlias).
+		WithHeader("Authorization", creator).
+		Expect().Status(http.StatusNoContent)
+	testRedirectNotFound(t, baseURL, ownAlias)
+
+	// Viewers only read.
+	e.GET("/url/"+adminAlias).
+		WithHeader("Authorization", viewer).
+		Expect().Status(http.StatusOK)
+	e.POST("/url").
+		WithJSON(save.Request{URL: gofakeit.URL()}).
+		WithHeader("Authorization", viewer).
+		Expect().Status(http.StatusForbidden)
+
+	// Admins manage everyone's links.
+	e.DELETE("/url/"+adminAlias).
+		WithHeader("Authorization", admin).
+		Expect().Status(http.StatusNoContent)
+}
+
+func TestURLShortener_TokenRevocation(t *testing.T) {
+	e, _ := newTestClient(t)
+	admin := "Bearer " + makeHS256JWT(t, testAppSecret, 1, time.Now().Add(10*time.Minute))
+	leaked := "Bearer " + makeJWT(t, jwt.MapClaims{
+		"user_id": 2,
+		"jti":     "leaked",
+		"exp":     time.Now().Add(10 * time.Minute).Unix(),
+	})
+	old := "Bearer " + makeJWT(t, jwt.MapClaims{
+		"user_id": 2,
+		"iat":     time.Now().Add(-time.Hour).Unix(),
+		"exp":     time.Now().Add(10 * time.Minute).Unix(),
+	})
+	fresh := "Bearer " + makeJWT(t, jwt.MapClaims{
+		"user_id": 2,
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(10 * time.Minute).Unix(),
+	})
+
+	for _, token := range []string{leaked, old, fresh} {
+		e.GET("/url/").WithHeader("Authorization", token).Expect().Status(http.StatusOK)
+	}
+
+	// Only admins revoke tokens.
+	e.POST("/url/tokens/revoked").
+		WithJSON(map[string]any{"jti": "leaked"}).
+		WithHeader("Authorization", fresh).
+		Expect().Status(http.StatusForbidden)
+
+	e.POST("/url/tokens/revoked").
+		WithJSON(map[string]any{"jti": "leaked", "user_id": 2}).
+		WithHeader("Authorization", admin).
+		Expect().Status(http.StatusOK).
+		JSON().Object().HasValue("status", "OK")
+	e.GET("/url/").WithHeader("Authorization", leaked).Expect().Status(http.StatusUnauthorized)
+
+	e.PUT("/url/tokens/cutoffs/2").
+		WithJSON(map[string]any{"issued_before": time.Now().Add(-time.Minute).Format(time.RFC3339)}).
+		WithHeader("Authorization", admin).
+		Expect().Status(http.StatusOK)
+	e.GET("/url/").WithHeader("Authorization", old).Expect().Status(http.StatusUnauthorized)
+	e.GET("/url/").WithHeader("Authorization", fresh).Expect().Status(http.StatusOK)
+
+	list := e.GET("/url/tokens/revoked").
+		WithHeader("Authorization", admin).
+		Expect().Status(http.StatusOK).
+		JSON().Object()
+	list.Value("revoked").Array().Value(0).Object().HasValue("jti", "leaked").HasValue("revoked_by", 1)
+	list.Value("cutoffs").Array().Value(0).Object().HasValue("user_id", 2)
+}
+
 type fakeSSO struct{}
 
 func (fakeSSO) IsAdmin(_ context.Context, userID int64) (bool, error) {
 	return userID == 1, nil
 }
 
+type noResolver struct{}
+
+func (noResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
 func newTestClient(t *testing.T) (*httpexpect.Expect, string) {
 	t.Helper()
 
 	dbPath := tempDBPath(t)
-	st, err := sqlite.New(dbPath)
+	st, err := sqlite.New(dbPath, testDefaultDomain)
+	require.NoError(t, err)
+
+	// Fake URLs from gofakeit do not resolve, so only literal addresses
+	// are checked against private ranges here.
+	policy, err := urlpolicy.New(urlpolicy.Options{
+		Schemes:     []string{"http", "https"},
+		DenyPrivate: true,
+		Resolver:    noResolver{},
+	})
+	require.NoError(t, err)
+
+	revocations, err := auth.NewRevocations(st, 0)
+	require.NoError(t, err)
+
+	tokens, err := auth.NewJWTVerifier(auth.JWTOptions{
+		HMACSecret:  []byte(testAppSecret),
+		Revocations: revocations,
+	})
 	require.NoError(t, err)
 
 	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo}))
-	r := router.New(log, st, fakeSSO{}, testAppSecret, 0)
+	r := router.New(
+		log,
+		st,
+		auth.NewRoles(fakeSSO{}, auth.RoleCreator, map[int64]auth.Role{3: auth.RoleViewer}),
+		domains.New(testDefaultDomain, []string{testOtherDomain}),
+		&url.URL{Scheme: "https", Host: testDefaultDomain},
+		redirect.NewPasswordGate([]byte(testAppSecret), time.Minute, throttle.New(5, time.Minute)),
+		policy,
+		nil,
+		tokens,
+		revocations,
+		0,
+		router.RateLimits{},
+		nil,
+	)
 
 	srv := httptest.NewServer(r)
 	t.Cleanup(srv.Close)
@@ -168,6 +468,14 @@ func makeHS256JWT(t *testing.T, secret string, userID int64, exp time.Time) stri
 	return signed
 }
 
+func makeJWT(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testAppSecret))
+	require.NoError(t, err)
+	return signed
+}
+
 func testRedirect(t *testing.T, baseURL string, alias string, urlToRedirect string) {
 	u, err := url.Parse(baseURL)
 	require.NoError(t, err)